@@ -11,6 +11,7 @@ func main() {
 	app := cli.CreateCLI()
 	err := app.Run(os.Args)
 	if err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 }
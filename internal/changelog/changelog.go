@@ -1,14 +1,8 @@
 package changelog
 
 import (
-	"bufio"
-	"bytes"
 	"errors"
 	"fmt"
-	"os"
-	"regexp"
-	"strings"
-	"text/template"
 	"time"
 
 	"github.com/xanzy/go-gitlab"
@@ -18,83 +12,96 @@ import (
 
 // Item contains changelog item
 type Item struct {
-	Package    string
-	Version    string
-	Release    string
-	Urgency    string
+	Package string
+	Version string
+	// Release contains the distributions separated by spaces
+	Release string
+	Urgency string
+	// Options contains the header options besides urgency
+	Options    []Option
 	Changes    string
 	Maintainer string
 	Date       string
+
+	// Line is the number of the item header line in the parsed file
+	Line int
+	// raw is the item text as it was parsed, rendered is the parsed item
+	// rendered in the canonical form to find out if the item was changed
+	raw      string
+	rendered string
+	// sep contains blank lines after the item
+	sep string
 }
 
-var (
-	rePackage        = regexp.MustCompile(`(Package)(\:)( *)(.*)`)
-	reItem           = regexp.MustCompile(`(?P<package>[\S]*)( *)\((?P<version>.*)\)( *)(?P<release>.*)\;( *)urgency=(?P<urgency>.*)([\n\r]*)(?P<changes>[\n\r\s\S]*)([\n\r]*)(\s+--\s+)(?P<maintainer>[\s\S]+\<[\S\@]+\>)(\s*)(?P<date>\w+\,\s\d+\s\w+\s\d+\s[\d\:]+\s[\+\d+]*)`)
-	reItemGroupNames = reItem.SubexpNames()
-)
+const changelogFileName = "debian/changelog"
+
+// AddOptions represents the options of adding a changelog item
+type AddOptions struct {
+	// Bump is the version part to increment when the version isn't set
+	Bump string
+	// Format is the changelog format, it's detected by the existing file when empty
+	Format string
+	// Changes are used instead of the changes found in the repository
+	Changes []Change
+	// DryRun prints the diff of the changelog instead of writing it
+	DryRun bool
+}
 
-func Add(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, newItem *Item) error {
-	changelog, err := os.Open("debian/changelog")
+func Add(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, newItem *Item, opt *AddOptions) error {
+	format, err := detectFormat(opt.Format)
 	if err != nil {
 		return err
 	}
-
-	var prevRecord *Item
-	prevRecord, err = readChangelogItem(changelog, 0)
+	doc, err := readDocument(format, false)
 	if err != nil {
-		fmt.Println("Warning:", err.Error())
+		return err
 	}
 
-	if newItem.Package == "" {
-		newItem.Package, err = determinePackage(prevRecord)
-		if err != nil {
-			return err
-		}
+	prevRecord := latestItem(doc)
+	if prevRecord == nil {
+		fmt.Println("Warning: could not find changelog item")
 	}
 
-	if newItem.Version == "" {
-		newItem.Version, err = determineVersion(prevRecord)
-		if err != nil {
-			return err
-		}
+	err = format.Complete(newItem, prevRecord)
+	if err != nil {
+		return err
 	}
 
-	if newItem.Release == "" {
-		newItem.Release, err = determineRelease(prevRecord)
+	if newItem.Version == "" {
+		newItem.Version, err = determineVersion(prevRecord, opt.Bump)
 		if err != nil {
 			return err
 		}
 	}
-
-	if newItem.Urgency == "" {
-		newItem.Urgency, err = determineUrgency(prevRecord)
-		if err != nil {
-			return err
-		}
+	err = checkVersion(prevRecord, newItem.Version)
+	if err != nil {
+		return err
 	}
 
 	if newItem.Changes == "" {
-		newItem.Changes, err = determineChanges(prevRecord, git, cfg, path)
-		if err != nil {
-			return err
+		changes := opt.Changes
+		if len(changes) == 0 {
+			changes, err = determineChanges(prevRecord, git, cfg, path)
+			if err != nil {
+				return err
+			}
 		}
+		newItem.Changes = doc.RenderChanges(changes)
 	}
 
-	if newItem.Maintainer == "" {
-		newItem.Maintainer, err = determineMaintainer(prevRecord)
-		if err != nil {
-			return err
-		}
-	}
+	doc.Add(newItem)
+	return writeChangelog(format.FileName(), doc, opt.DryRun)
+}
 
-	if newItem.Date == "" {
-		newItem.Date, err = determineDate(prevRecord)
-		if err != nil {
-			return err
+// latestItem returns the latest item having a valid version, so the
+// Unreleased section of a Markdown changelog is skipped
+func latestItem(doc Document) *Item {
+	for _, item := range doc.Entries() {
+		if _, err := ParseVersion(item.Version); err == nil {
+			return item
 		}
 	}
-
-	return appendChangelogItem(changelog, newItem)
+	return nil
 }
 
 func determinePackage(prevItem *Item) (string, error) {
@@ -104,12 +111,35 @@ func determinePackage(prevItem *Item) (string, error) {
 	return prevItem.Package, nil
 }
 
-func determineVersion(prevItem *Item) (string, error) {
+func determineVersion(prevItem *Item, bump string) (string, error) {
 	if prevItem == nil || prevItem.Version == "" {
 		return "", errors.New("error: empty prevItem.Version")
 	}
-	// TODO: parse version and increment last part of it
-	return prevItem.Version, nil
+	version, err := ParseVersion(prevItem.Version)
+	if err != nil {
+		return "", err
+	}
+	version, err = version.Bump(bump)
+	if err != nil {
+		return "", err
+	}
+	return version.String(), nil
+}
+
+// checkVersion makes sure the new version is greater than the previous one
+func checkVersion(prevItem *Item, newVersion string) error {
+	if prevItem == nil || prevItem.Version == "" {
+		_, err := ParseVersion(newVersion)
+		return err
+	}
+	c, err := CompareVersions(newVersion, prevItem.Version)
+	if err != nil {
+		return err
+	}
+	if c <= 0 {
+		return fmt.Errorf("error: version %s should be greater than the previous one %s", newVersion, prevItem.Version)
+	}
+	return nil
 }
 
 func determineRelease(prevItem *Item) (string, error) {
@@ -142,135 +172,21 @@ func determineDate(prevItem *Item) (string, error) {
 }
 
 func getPackageName() (string, error) {
-	control, err := os.Open("debian/control")
+	control, err := ReadControl(controlFileName)
 	if err != nil {
 		return "", err
 	}
-
-	controlText := make([]byte, 0, 1024)
-	_, err = control.Read(controlText)
-	if err != nil {
-		return "", err
-	}
-
-	out := rePackage.FindAllStringSubmatch(string(controlText), -1)
-	for i, v := range out {
-		value := strings.TrimSpace(v[len(v)-1])
-		fmt.Printf("%d: %s\n", i, value)
-	}
-
-	return "", errors.New("package name not found")
+	return control.Package(), nil
 }
 
-func readChangelogItem(changelog *os.File, idx int) (*Item, error) {
+// readChangelogItem returns the item by its index, the latest item is 0
+func readChangelogItem(changelog Document, idx int) (*Item, error) {
 	if changelog == nil {
-		return nil, errors.New("error: nil changelog file pointer")
+		return nil, errors.New("error: nil changelog")
 	}
-
-	count := 0
-	offset := int64(0)
-	var buf string
-	newItem := Item{}
-
-	_, err := changelog.Seek(offset, 0)
-	scanner := bufio.NewScanner(changelog)
-
-	for scanner.Scan() {
-		changelogText := scanner.Text()
-		if err == nil {
-			if len(buf) > 0 {
-				buf += "\n"
-			}
-			buf += changelogText
-		}
-
-		for _, match := range reItem.FindAllStringSubmatch(buf, -1) {
-			if count < idx {
-				buf = buf[len(match[0]):len(buf)]
-				offset += int64(len(match[0]))
-				count++
-				continue
-			}
-			for groupIdx, value := range match {
-				switch reItemGroupNames[groupIdx] {
-				case "package":
-					newItem.Package = value
-				case "version":
-					newItem.Version = value
-				case "release":
-					newItem.Release = value
-				case "urgency":
-					newItem.Urgency = value
-				case "changes":
-					newItem.Changes = strings.Trim(strings.Trim(value, "\n"), "\r")
-				case "maintainer":
-					newItem.Maintainer = value
-				case "date":
-					newItem.Date = value
-				}
-			}
-			return &newItem, nil
-		}
-	}
-	return nil, errors.New("error: could not find changelog item")
-}
-
-func appendChangelogItem(changelog *os.File, newItem *Item) error {
-	tpl, err := template.New("changelog").Parse(`{{.Package}} ({{.Version}}) {{.Release}}; urgency={{.Urgency}}
-
-{{.Changes}}
-
- -- {{.Maintainer}}  {{.Date}}
-
-`)
-	if err != nil {
-		return err
+	items := changelog.Entries()
+	if idx < 0 || idx >= len(items) {
+		return nil, errors.New("error: could not find changelog item")
 	}
-
-	buf := &bytes.Buffer{}
-
-	err = tpl.Execute(buf, newItem)
-	if err != nil {
-		return err
-	}
-
-	_, err = changelog.Seek(0, 0)
-	if err != nil {
-		return err
-	}
-
-	newChangelog, err := os.Create("debian/changelog.new")
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = newChangelog.Close()
-	}()
-
-	// append at the start
-	_, err = newChangelog.Write(buf.Bytes())
-	if err != nil {
-		panic(err)
-	}
-	scanner := bufio.NewScanner(changelog)
-
-	// read the file to be appended to and output all of it
-	for scanner.Scan() {
-
-		_, err = newChangelog.WriteString(scanner.Text())
-		_, err = newChangelog.WriteString("\n")
-	}
-
-	if err = scanner.Err(); err != nil {
-		panic(err)
-	}
-	// ensure all lines are written
-	_ = newChangelog.Sync()
-
-	// overwrite the old file with the new one
-	err = os.Rename("debian/changelog.new", "debian/changelog")
-	if err != nil {
-		panic(err)
-	}
-	return nil
+	return items[idx], nil
 }
@@ -2,60 +2,250 @@ package changelog
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/xanzy/go-gitlab"
 
+	"github.com/lexycore/gitlab-tools/internal/client"
 	"github.com/lexycore/gitlab-tools/internal/config"
+	"github.com/lexycore/gitlab-tools/internal/operation"
 )
 
-const defaultChanges = "  some changes were made"
+const defaultChanges = "some changes were made"
 
-func determineChanges(prevItem *Item, gitClient *gitlab.Client, cfg *config.Config, path *config.GitLabPath) (string, error) {
-	// if prevItem == nil || prevItem.Changes == "" {
-	// 	return "some changes were made", nil
-	// }
+// determineChanges lists merge requests merged since the tag of the previous
+// changelog item, it falls back to commit subjects if there is no GitLab remote
+func determineChanges(prevItem *Item, gitClient *gitlab.Client, cfg *config.Config, path *config.GitLabPath) ([]Change, error) {
+	defaults := []Change{{Category: CategoryChanged, Text: defaultChanges}}
 	repo, err := git.PlainOpen(".")
 	if err != nil {
-		return defaultChanges, nil
+		return defaults, nil
 	}
 
-	// TODO: get merged MRs between previous and new changelog items
-	fmt.Println("Remotes")
-	remotes, err := repo.Remotes()
+	var tagCommit *object.Commit
+	if prevItem != nil {
+		tagCommit = findVersionTag(repo, prevItem.Version)
+	}
+	commits, err := commitsSince(repo, tagCommit)
+	if err != nil || len(commits) == 0 {
+		return defaults, nil
+	}
+
+	changes, err := mergeRequestChanges(repo, commits, gitClient, cfg)
 	if err != nil {
-		return defaultChanges, nil
+		fmt.Fprintln(os.Stderr, "Warning:", strings.TrimPrefix(err.Error(), "error: "))
+	}
+	if len(changes) == 0 {
+		changes = commitChanges(commits)
 	}
+	if len(changes) == 0 {
+		return defaults, nil
+	}
+	return changes, nil
+}
 
-	for i, remote := range remotes {
-		for j, url := range remote.Config().URLs {
-			fmt.Println(i, j, url)
+// versionTags returns tag names a version is usually tagged with
+func versionTags(version string) []string {
+	tags := []string{
+		version,
+		"v" + version,
+		// DEP-14 tag of the Debian version
+		"debian/" + strings.NewReplacer(":", "%", "~", "_").Replace(version),
+	}
+	if v, err := ParseVersion(version); err == nil && v.Revision != "" {
+		tags = append(tags, v.Upstream, "v"+v.Upstream)
+	}
+	return tags
+}
+
+// findVersionTag returns the commit of the tag matching the version or nil
+func findVersionTag(repo *git.Repository, version string) *object.Commit {
+	for _, name := range versionTags(version) {
+		ref, err := repo.Reference(plumbing.NewTagReferenceName(name), true)
+		if err != nil {
+			continue
+		}
+		hash := ref.Hash()
+		if tag, err := repo.TagObject(hash); err == nil {
+			hash = tag.Target
+		}
+		commit, err := repo.CommitObject(hash)
+		if err == nil {
+			return commit
 		}
 	}
+	return nil
+}
 
-	fmt.Println("Tags")
-	tags, err := repo.Tags()
+// commitsSince returns commits reachable from HEAD but not from the given commit,
+// the newest first
+func commitsSince(repo *git.Repository, since *object.Commit) ([]*object.Commit, error) {
+	head, err := repo.Head()
 	if err != nil {
-		return defaultChanges, nil
+		return nil, err
 	}
-
-	err = tags.ForEach(func(ref *plumbing.Reference) error {
-		fmt.Println(ref.Name(), ref.Hash(), ref.Type(), ref.String(), ref.Target())
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	released := make(map[plumbing.Hash]bool)
+	if since != nil {
+		err = object.NewCommitPreorderIter(since, nil, nil).ForEach(func(commit *object.Commit) error {
+			released[commit.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	commits := make([]*object.Commit, 0)
+	err = object.NewCommitPreorderIter(headCommit, released, nil).ForEach(func(commit *object.Commit) error {
+		commits = append(commits, commit)
 		return nil
 	})
-
-	fmt.Println("CommitObjects")
-	objs, err := repo.CommitObjects()
 	if err != nil {
-		return defaultChanges, nil
+		return nil, err
 	}
+	return commits, nil
+}
 
-	err = objs.ForEach(func(commit *object.Commit) error {
-		fmt.Println(commit.Committer, commit.Hash, commit.ParentHashes, commit.Message, commit.Author)
-		return nil
+// commitChanges returns subjects of non-merge commits, the oldest first
+func commitChanges(commits []*object.Commit) []Change {
+	changes := make([]Change, 0, len(commits))
+	for i := len(commits) - 1; i >= 0; i-- {
+		if commits[i].NumParents() > 1 {
+			continue
+		}
+		subject := strings.TrimSpace(strings.SplitN(commits[i].Message, "\n", 2)[0])
+		if subject != "" {
+			changes = append(changes, Change{Category: categorize(subject, nil), Text: subject})
+		}
+	}
+	return changes
+}
+
+// mergeRequestChanges returns merge requests merged with the given commits, the
+// oldest first, as "Title (!123, @author)" categorized by their labels
+func mergeRequestChanges(repo *git.Repository, commits []*object.Commit, gitClient *gitlab.Client, cfg *config.Config) ([]Change, error) {
+	project, gitClient, err := remoteProject(repo, gitClient, cfg)
+	if err != nil || project == "" {
+		return nil, err
+	}
+	hashes := make(map[string]bool, len(commits))
+	since := commits[0].Committer.When
+	for _, commit := range commits {
+		hashes[commit.Hash.String()] = true
+		if commit.Committer.When.Before(since) {
+			since = commit.Committer.When
+		}
+	}
+	mrs, err := operation.MergedWithCommits(gitClient, project, "", hashes, &since)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(mrs, func(i, j int) bool {
+		return mergedAt(mrs[i]).Before(mergedAt(mrs[j]))
 	})
+	changes := make([]Change, 0, len(mrs))
+	for _, mr := range mrs {
+		author := ""
+		if mr.Author != nil {
+			author = ", @" + mr.Author.Username
+		}
+		title := strings.TrimSpace(mr.Title)
+		changes = append(changes, Change{
+			Category: categorize(title, mr.Labels),
+			Text:     fmt.Sprintf("%s (!%d%s)", title, mr.IID, author),
+		})
+	}
+	return changes, nil
+}
+
+func mergedAt(mr *gitlab.MergeRequest) time.Time {
+	if mr.MergedAt == nil {
+		return time.Time{}
+	}
+	return *mr.MergedAt
+}
+
+// remoteProject returns the project path of the origin remote along with
+// the client of its GitLab server, the project is empty if there is no remote;
+// the client is created here unless it's given for the same server
+func remoteProject(repo *git.Repository, gitClient *gitlab.Client, cfg *config.Config) (string, *gitlab.Client, error) {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return "", nil, nil
+	}
+	server, project, ok := parseRemoteURL(remote.Config().URLs[0])
+	if !ok {
+		return "", nil, nil
+	}
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	switch {
+	case !sameHost(cfg.GitLabURL, server):
+		gitClient, err = client.InitClient(cfg.ForServer(server))
+	case gitClient == nil:
+		serverCfg := *cfg
+		// the anonymous access is used without the token
+		err = serverCfg.ResolveToken()
+		if err == nil || err == config.ErrNoToken {
+			gitClient, err = client.InitClient(&serverCfg)
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return project, gitClient, nil
+}
+
+// parseRemoteURL returns the server URL and the project path of a remote URL,
+// both URL and scp-like (git@host:group/project.git) forms are supported
+func parseRemoteURL(remoteURL string) (string, string, bool) {
+	scheme := "https"
+	var host, path string
+	if idx := strings.Index(remoteURL, "://"); idx >= 0 {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return "", "", false
+		}
+		host, path = u.Hostname(), u.Path
+		switch u.Scheme {
+		case "http", "https":
+			// the API is served on the same port as the repository
+			scheme, host = u.Scheme, u.Host
+		case "file":
+			return "", "", false
+		}
+	} else if idx := strings.Index(remoteURL, ":"); idx > 0 && !strings.Contains(remoteURL[:idx], "/") {
+		host, path = remoteURL[:idx], remoteURL[idx+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return "", "", false
+	}
+	return fmt.Sprintf("%s://%s/", scheme, host), path, true
+}
 
-	return prevItem.Changes, nil
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Hostname() == ub.Hostname()
 }
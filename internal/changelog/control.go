@@ -0,0 +1,144 @@
+package changelog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const controlFileName = "debian/control"
+
+// Field is a field of a deb822 paragraph
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Paragraph is a deb822 paragraph (stanza), the fields keep their order
+type Paragraph struct {
+	Fields []Field
+	// Line is the number of the first line of the paragraph
+	Line int
+}
+
+// Get returns the field value, field names are case-insensitive
+func (p *Paragraph) Get(name string) string {
+	for _, field := range p.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Control contains the paragraphs of the debian/control file
+type Control struct {
+	Source   *Paragraph
+	Binaries []*Paragraph
+}
+
+// Package returns the source package name
+func (c *Control) Package() string {
+	return c.Source.Get("Source")
+}
+
+// Maintainer returns the source package maintainer
+func (c *Control) Maintainer() string {
+	return c.Source.Get("Maintainer")
+}
+
+// BinaryPackages returns names of the binary packages
+func (c *Control) BinaryPackages() []string {
+	names := make([]string, 0, len(c.Binaries))
+	for _, binary := range c.Binaries {
+		names = append(names, binary.Get("Package"))
+	}
+	return names
+}
+
+// ReadControl reads the control file
+func ReadControl(path string) (*Control, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return ParseControl(file)
+}
+
+// ParseControl parses the control file, the first paragraph describes the
+// source package and the rest of them describe the binary packages
+func ParseControl(r io.Reader) (*Control, error) {
+	paragraphs, err := ParseDeb822(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(paragraphs) == 0 {
+		return nil, errors.New("control file: no paragraphs found")
+	}
+	control := &Control{
+		Source:   paragraphs[0],
+		Binaries: paragraphs[1:],
+	}
+	if control.Package() == "" {
+		return nil, fmt.Errorf("control file line %d: the first paragraph has no Source field", control.Source.Line)
+	}
+	for _, binary := range control.Binaries {
+		if binary.Get("Package") == "" {
+			return nil, fmt.Errorf("control file line %d: the paragraph has no Package field", binary.Line)
+		}
+	}
+	return control, nil
+}
+
+// ParseDeb822 parses paragraphs of a deb822 file: paragraphs are separated by
+// blank lines, lines starting with # are comments, continuation lines start
+// with a space or a tab and " ." stands for an empty line of the value
+func ParseDeb822(r io.Reader) ([]*Paragraph, error) {
+	paragraphs := make([]*Paragraph, 0)
+	var paragraph *Paragraph
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.TrimSpace(line) == "":
+			paragraph = nil
+		case strings.HasPrefix(line, "#"):
+			continue
+		case line[0] == ' ' || line[0] == '\t':
+			if paragraph == nil || len(paragraph.Fields) == 0 {
+				return nil, fmt.Errorf("control file line %d: continuation line without a field", lineNum)
+			}
+			value := strings.TrimSpace(line)
+			if value == "." {
+				value = ""
+			}
+			field := &paragraph.Fields[len(paragraph.Fields)-1]
+			field.Value += "\n" + value
+		default:
+			idx := strings.Index(line, ":")
+			if idx <= 0 {
+				return nil, fmt.Errorf("control file line %d: malformed field", lineNum)
+			}
+			if paragraph == nil {
+				paragraph = &Paragraph{Line: lineNum}
+				paragraphs = append(paragraphs, paragraph)
+			}
+			paragraph.Fields = append(paragraph.Fields, Field{
+				Name:  strings.TrimSpace(line[:idx]),
+				Value: strings.TrimSpace(line[idx+1:]),
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return paragraphs, nil
+}
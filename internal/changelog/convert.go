@@ -0,0 +1,185 @@
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	reBulletStart = regexp.MustCompile(`^\s*[-*+]\s+`)
+	reAuthorLine  = regexp.MustCompile(`^\s*\[\s.*\s\]\s*$`)
+)
+
+// ConvertOptions represents the options of converting a changelog
+type ConvertOptions struct {
+	// From is the format of the source changelog, it's detected when empty
+	From string
+	To   string
+	// Write updates the changelog file of the target format instead of
+	// printing the converted changelog
+	Write bool
+	// DryRun prints the diff of the target changelog instead of writing it
+	DryRun bool
+}
+
+// Convert translates the changelog items to another format, when the target
+// changelog exists only the items newer than its latest item are added
+func Convert(w io.Writer, opt *ConvertOptions) error {
+	if opt.To == "" {
+		return errors.New("error: target changelog format is not set")
+	}
+	to, err := detectFormat(opt.To)
+	if err != nil {
+		return err
+	}
+	from, err := detectFormat(opt.From, to.Name())
+	if err != nil {
+		return err
+	}
+	if from.Name() == to.Name() {
+		return fmt.Errorf("error: changelog is already in %s format", to.Name())
+	}
+
+	source, err := readDocument(from, false)
+	if err != nil {
+		return err
+	}
+	target, err := readDocument(to, true)
+	if err != nil {
+		return err
+	}
+
+	items := newerItems(source.Entries(), latestItem(target))
+	for i := len(items) - 1; i >= 0; i-- {
+		item, err := convertItem(items[i], from, to, target)
+		if err != nil {
+			return err
+		}
+		target.Add(item)
+	}
+
+	if !opt.Write && !opt.DryRun {
+		_, err = target.WriteTo(w)
+		return err
+	}
+	if opt.DryRun {
+		return writeChangelog(to.FileName(), target, true)
+	}
+	// debian/changelog may be the first file of the debian directory
+	err = os.MkdirAll(filepath.Dir(to.FileName()), 0755)
+	if err != nil {
+		return err
+	}
+	err = writeChangelog(to.FileName(), target, false)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("converted %d items to %s\n", len(items), to.FileName())
+	return nil
+}
+
+// readDocument reads the changelog of the format, a missing optional changelog is empty
+func readDocument(format Format, optional bool) (Document, error) {
+	file, err := os.Open(format.FileName())
+	if optional && os.IsNotExist(err) {
+		return format.Parse(strings.NewReader(""))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return format.Parse(file)
+}
+
+// newerItems returns the items with valid versions greater than the latest one
+func newerItems(items []*Item, latest *Item) []*Item {
+	newer := make([]*Item, 0, len(items))
+	for _, item := range items {
+		if _, err := ParseVersion(item.Version); err != nil {
+			continue
+		}
+		if latest != nil {
+			c, err := CompareVersions(item.Version, latest.Version)
+			if err != nil || c <= 0 {
+				continue
+			}
+		}
+		newer = append(newer, item)
+	}
+	return newer
+}
+
+// convertItem copies the item converting its date and changes, the fields
+// the source format doesn't have are completed by the target format
+func convertItem(item *Item, from, to Format, target Document) (*Item, error) {
+	converted := &Item{
+		Package:    item.Package,
+		Version:    item.Version,
+		Release:    item.Release,
+		Urgency:    item.Urgency,
+		Options:    item.Options,
+		Maintainer: item.Maintainer,
+		Changes:    target.RenderChanges(parseChanges(item.Changes)),
+	}
+	if item.Date != "" {
+		date, err := from.ParseDate(item.Date)
+		if err != nil {
+			return nil, fmt.Errorf("error: version %s: %s", item.Version, err.Error())
+		}
+		converted.Date = to.FormatDate(date)
+	}
+	// the converted items are the released history
+	if converted.Release == "" {
+		converted.Release = defaultDistribution
+	}
+	if converted.Urgency == "" {
+		converted.Urgency = "medium"
+	}
+	if converted.Maintainer == "" {
+		converted.Maintainer = currentMaintainer("")
+	}
+	err := to.Complete(converted, latestItem(target))
+	if err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// parseChanges splits the changes text of any format into bullets, the
+// continuation lines are joined, "### Category" headings set the category
+// and Debian "[ Name ]" author lines are dropped
+func parseChanges(text string) []Change {
+	changes := make([]Change, 0)
+	category := ""
+	for _, line := range strings.Split(text, "\n") {
+		if match := reSubsection.FindStringSubmatch(line); match != nil {
+			category = match[1]
+			continue
+		}
+		if strings.TrimSpace(line) == "" || reAuthorLine.MatchString(line) {
+			continue
+		}
+		if loc := reBulletStart.FindStringIndex(line); loc != nil || len(changes) == 0 {
+			text := strings.TrimSpace(line)
+			if loc != nil {
+				text = strings.TrimSpace(line[loc[1]:])
+			}
+			c := category
+			if c == "" {
+				c = categorize(text, nil)
+			}
+			changes = append(changes, Change{Category: c, Text: text})
+			continue
+		}
+		last := &changes[len(changes)-1]
+		last.Text += " " + strings.TrimSpace(line)
+	}
+	return changes
+}
@@ -0,0 +1,221 @@
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var reHeader = regexp.MustCompile(`^(\S+)\s+\(([^()\s]+)\)((?:\s+[^\s;]+)*)\s*;(.*)$`)
+
+// Changelog contains the items of a Debian changelog file
+type Changelog struct {
+	Items []*Item
+	// Tail contains the text after the last item which isn't a changelog item,
+	// e.g. an old changelog or editor settings
+	Tail string
+	// head contains blank lines before the first item
+	head string
+}
+
+// Option is a key=value option of the item header besides urgency
+type Option struct {
+	Key   string
+	Value string
+}
+
+// ParseError reports a malformed changelog line
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("changelog line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads all the items of a Debian changelog
+func Parse(r io.Reader) (*Changelog, error) {
+	changelog := &Changelog{
+		Items: make([]*Item, 0),
+	}
+	reader := bufio.NewReader(r)
+	var item *Item
+	raw := &strings.Builder{}
+	changes := make([]string, 0)
+	lineNum := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		lineNum++
+		text := strings.TrimRight(line, "\r\n")
+		switch {
+		case item == nil && strings.TrimSpace(text) == "":
+			// blank lines separate items
+			if n := len(changelog.Items); n > 0 {
+				changelog.Items[n-1].sep += line
+			} else {
+				changelog.head += line
+			}
+		case item == nil:
+			item = parseHeader(text)
+			if item == nil {
+				if len(changelog.Items) == 0 {
+					return nil, &ParseError{Line: lineNum, Msg: "malformed item header"}
+				}
+				// the rest of the file isn't the changelog in this format
+				rest, err := io.ReadAll(reader)
+				if err != nil {
+					return nil, err
+				}
+				changelog.Tail = line + string(rest)
+				return changelog, nil
+			}
+			item.Line = lineNum
+			raw.Reset()
+			raw.WriteString(line)
+			changes = changes[:0]
+		case strings.HasPrefix(text, " --"):
+			raw.WriteString(line)
+			item.Maintainer, item.Date = parseTrailer(text)
+			item.Changes = strings.Trim(strings.Join(changes, "\n"), "\n")
+			item.raw = raw.String()
+			item.rendered = renderDebian(item)
+			changelog.Items = append(changelog.Items, item)
+			item = nil
+		default:
+			if reHeader.MatchString(text) && !strings.HasPrefix(text, " ") {
+				return nil, &ParseError{Line: lineNum, Msg: "item header before the trailer line of the previous item"}
+			}
+			raw.WriteString(line)
+			changes = append(changes, text)
+		}
+	}
+	if item != nil {
+		return nil, &ParseError{Line: lineNum, Msg: "unexpected end of file, missing trailer line"}
+	}
+	return changelog, nil
+}
+
+// parseTrailer splits the trailer line into the maintainer and the date,
+// the date follows the maintainer email (or two spaces if there is no email)
+func parseTrailer(text string) (string, string) {
+	text = strings.TrimSpace(strings.TrimPrefix(text, " --"))
+	idx := strings.LastIndex(text, ">")
+	if idx < 0 {
+		idx = strings.Index(text, "  ") - 1
+	}
+	if idx < 0 {
+		return text, ""
+	}
+	return strings.TrimSpace(text[:idx+1]), strings.TrimSpace(text[idx+1:])
+}
+
+// parseHeader parses the item header line, it returns nil if the line isn't a header
+func parseHeader(text string) *Item {
+	match := reHeader.FindStringSubmatch(text)
+	if match == nil {
+		return nil
+	}
+	item := &Item{
+		Package: match[1],
+		Version: match[2],
+		Release: strings.Join(strings.Fields(match[3]), " "),
+	}
+	for _, option := range strings.Split(match[4], ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		kv := strings.SplitN(option, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		value := ""
+		if len(kv) > 1 {
+			value = strings.TrimSpace(kv[1])
+		}
+		if strings.EqualFold(key, "urgency") && item.Urgency == "" {
+			item.Urgency = value
+			continue
+		}
+		item.Options = append(item.Options, Option{Key: key, Value: value})
+	}
+	return item
+}
+
+// WriteTo writes the changelog, unchanged items are written exactly as they were read
+func (c *Changelog) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(c.head)
+	for _, item := range c.Items {
+		buf.WriteString(item.text(renderDebian))
+		if item.sep == "" && item.raw == "" {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(item.sep)
+	}
+	buf.WriteString(c.Tail)
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the changelog text
+func (c *Changelog) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	_, _ = c.WriteTo(buf)
+	return buf.Bytes()
+}
+
+// Entries implements Document
+func (c *Changelog) Entries() []*Item {
+	return c.Items
+}
+
+// Add implements Document
+func (c *Changelog) Add(item *Item) {
+	c.Items = append([]*Item{item}, c.Items...)
+}
+
+// RenderChanges implements Document, every change is a "  * " bullet
+func (c *Changelog) RenderChanges(changes []Change) string {
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, "  * "+change.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Distributions returns the distributions of the item
+func (i *Item) Distributions() []string {
+	return strings.Fields(i.Release)
+}
+
+// String returns the item in the Debian changelog format
+func (i *Item) String() string {
+	return i.text(renderDebian)
+}
+
+// text returns the original text of the item unless it was modified
+func (i *Item) text(render func(i *Item) string) string {
+	rendered := render(i)
+	if i.raw != "" && rendered == i.rendered {
+		return i.raw
+	}
+	return rendered
+}
+
+func renderDebian(i *Item) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s (%s) %s; urgency=%s", i.Package, i.Version, i.Release, i.Urgency)
+	for _, option := range i.Options {
+		fmt.Fprintf(b, ", %s=%s", option.Key, option.Value)
+	}
+	fmt.Fprintf(b, "\n\n%s\n\n -- %s  %s\n", i.Changes, i.Maintainer, i.Date)
+	return b.String()
+}
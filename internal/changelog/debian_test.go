@@ -0,0 +1,117 @@
+package changelog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		file  string
+		items int
+		// first is the expected first item, its changes aren't compared
+		first Item
+		tail  string
+	}{
+		{
+			file:  "nodejs.changelog",
+			items: 1,
+			first: Item{
+				Package:    "nodejs",
+				Version:    "20.19.5-1nodesource1",
+				Release:    "stable",
+				Urgency:    "low",
+				Maintainer: "NSolid <nsolid-gpg@nodesource.com>",
+				Date:       "Wed, 03 Sep 2025 12:56:43 -0700",
+				Line:       1,
+			},
+		},
+		{
+			file:  "linux-atm.changelog",
+			items: 2,
+			first: Item{
+				Package:    "linux-atm",
+				Version:    "1:2.5.1-4",
+				Release:    "unstable",
+				Urgency:    "medium",
+				Maintainer: "Gianfranco Costamagna <locutusofborg@debian.org>",
+				Date:       "Fri, 19 Jul 2019 11:14:38 +0200",
+				Line:       1,
+			},
+			tail: "# Older entries have been removed from this changelog.\n# To read the complete changelog use `apt changelog libatm1`.\n",
+		},
+		{
+			file:  "sed.changelog",
+			items: 4,
+			first: Item{
+				Package:    "sed",
+				Version:    "4.9-1",
+				Release:    "unstable",
+				Urgency:    "medium",
+				Maintainer: "Clint Adams <clint@debian.org>",
+				Date:       "Thu, 05 Jan 2023 14:55:25 -0500",
+				Line:       1,
+			},
+			tail: "# Older entries have been removed from this changelog.\n# To read the complete changelog use `apt changelog sed`.\n",
+		},
+		{
+			file:  "mawk.changelog",
+			items: 3,
+			first: Item{
+				Package:    "mawk",
+				Version:    "1.3.3-2",
+				Release:    "frozen unstable",
+				Urgency:    "low",
+				Maintainer: "James Troup <awkmaint@nocrew.org>",
+				Date:       "Thu, 30 Apr 1998 16:02:45 +0200",
+				Line:       1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			golden, err := ioutil.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := Parse(bytes.NewReader(golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed.Items) != tt.items {
+				t.Fatalf("parsed %d items, want %d", len(parsed.Items), tt.items)
+			}
+			first := parsed.Items[0]
+			got := Item{
+				Package:    first.Package,
+				Version:    first.Version,
+				Release:    first.Release,
+				Urgency:    first.Urgency,
+				Maintainer: first.Maintainer,
+				Date:       first.Date,
+				Line:       first.Line,
+			}
+			if !reflect.DeepEqual(got, tt.first) {
+				t.Errorf("first item is %+v, want %+v", got, tt.first)
+			}
+			if first.Changes == "" {
+				t.Error("first item has no changes")
+			}
+			if parsed.Tail != tt.tail {
+				t.Errorf("tail is %q, want %q", parsed.Tail, tt.tail)
+			}
+
+			buf := &bytes.Buffer{}
+			_, err = parsed.WriteTo(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), golden) {
+				t.Errorf("written changelog differs from %s:\n%s", tt.file, buf.String())
+			}
+		})
+	}
+}
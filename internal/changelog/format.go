@@ -0,0 +1,197 @@
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lexycore/gitlab-tools/internal/util"
+)
+
+// Changelog formats
+const (
+	FormatDebian   = "debian"
+	FormatMarkdown = "markdown"
+	FormatRPM      = "rpm"
+)
+
+// Change categories of Keep a Changelog
+const (
+	CategoryAdded      = "Added"
+	CategoryChanged    = "Changed"
+	CategoryDeprecated = "Deprecated"
+	CategoryRemoved    = "Removed"
+	CategoryFixed      = "Fixed"
+	CategorySecurity   = "Security"
+)
+
+var categories = []string{
+	CategoryAdded,
+	CategoryChanged,
+	CategoryDeprecated,
+	CategoryRemoved,
+	CategoryFixed,
+	CategorySecurity,
+}
+
+// Change is a single entry of the changelog item
+type Change struct {
+	Category string `json:"category" yaml:"category"`
+	Text     string `json:"text" yaml:"text"`
+}
+
+// Format reads the changelog file of a particular format
+type Format interface {
+	Name() string
+	FileName() string
+	Parse(r io.Reader) (Document, error)
+	// Complete fills the empty fields of the new item besides the version and the changes
+	Complete(newItem *Item, prevItem *Item) error
+	// FormatDate and ParseDate convert the item dates of the format
+	FormatDate(t time.Time) string
+	ParseDate(s string) (time.Time, error)
+}
+
+// Document is a parsed changelog file
+type Document interface {
+	// Entries returns the changelog items, the latest first
+	Entries() []*Item
+	// Add inserts the new item as the latest one
+	Add(item *Item)
+	// RenderChanges formats the changes in the style of the document
+	RenderChanges(changes []Change) string
+	io.WriterTo
+}
+
+var formats = []Format{
+	&debianFormat{},
+	&markdownFormat{},
+	&rpmFormat{},
+}
+
+// detectFormat returns the format by its name or the format of the first
+// existing changelog file if the name is empty, skipping the excluded formats
+func detectFormat(name string, exclude ...string) (Format, error) {
+	for _, format := range formats {
+		if name == "" {
+			if util.ContainsString(&exclude, format.Name()) {
+				continue
+			}
+			if _, err := os.Stat(format.FileName()); err == nil {
+				return format, nil
+			}
+		} else if format.Name() == name {
+			return format, nil
+		}
+	}
+	if name == "" {
+		names := make([]string, 0, len(formats))
+		for _, format := range formats {
+			names = append(names, format.FileName())
+		}
+		return nil, fmt.Errorf("error: no changelog found, looked for %s", strings.Join(names, ", "))
+	}
+	return nil, fmt.Errorf("error: unknown changelog format '%s', use %s, %s or %s", name, FormatDebian, FormatMarkdown, FormatRPM)
+}
+
+var (
+	reFixed = regexp.MustCompile(`(?i)^(fix|bug|hotfix)`)
+	reAdded = regexp.MustCompile(`(?i)^(feat|add|new)`)
+)
+
+// categorize determines the category of the change by its labels or by its
+// conventional commit prefix
+func categorize(title string, labels []string) string {
+	for _, label := range labels {
+		switch strings.ToLower(label) {
+		case "security":
+			return CategorySecurity
+		case "bug", "bugfix", "fix":
+			return CategoryFixed
+		case "feature", "enhancement":
+			return CategoryAdded
+		case "deprecation", "deprecated":
+			return CategoryDeprecated
+		case "removal", "removed":
+			return CategoryRemoved
+		}
+	}
+	switch {
+	case reFixed.MatchString(title):
+		return CategoryFixed
+	case reAdded.MatchString(title):
+		return CategoryAdded
+	}
+	return CategoryChanged
+}
+
+// debianFormat is the debian/changelog format
+type debianFormat struct{}
+
+func (f *debianFormat) Name() string {
+	return FormatDebian
+}
+
+func (f *debianFormat) FileName() string {
+	return changelogFileName
+}
+
+func (f *debianFormat) Parse(r io.Reader) (Document, error) {
+	return Parse(r)
+}
+
+func (f *debianFormat) Complete(newItem *Item, prevItem *Item) error {
+	var err error
+	if newItem.Package == "" {
+		newItem.Package, err = determinePackage(prevItem)
+		if err != nil {
+			return err
+		}
+	}
+
+	if newItem.Release == "" {
+		newItem.Release, err = determineRelease(prevItem)
+		if err != nil {
+			return err
+		}
+	}
+
+	if newItem.Urgency == "" {
+		newItem.Urgency, err = determineUrgency(prevItem)
+		if err != nil {
+			return err
+		}
+	}
+
+	if newItem.Maintainer == "" {
+		newItem.Maintainer, err = determineMaintainer(prevItem)
+		if err != nil {
+			return err
+		}
+	}
+
+	if newItem.Date == "" {
+		newItem.Date, err = determineDate(prevItem)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *debianFormat) FormatDate(t time.Time) string {
+	return t.Format(time.RFC1123Z)
+}
+
+// ParseDate accepts the days with and without the leading zero
+func (f *debianFormat) ParseDate(s string) (time.Time, error) {
+	s = strings.Join(strings.Fields(s), " ")
+	t, err := time.Parse(time.RFC1123Z, s)
+	if err != nil {
+		t, err = time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", s)
+	}
+	return t, err
+}
@@ -0,0 +1,262 @@
+package changelog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lexycore/gitlab-tools/internal/util"
+)
+
+// Finding severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Lint checks
+const (
+	CheckHeader     = "malformed-header"
+	CheckLine       = "malformed-line"
+	CheckTrailer    = "missing-trailer"
+	CheckVersion    = "invalid-version"
+	CheckOrder      = "version-order"
+	CheckUrgency    = "unknown-urgency"
+	CheckDate       = "invalid-date"
+	CheckWeekday    = "date-weekday"
+	CheckSpacing    = "trailer-spacing"
+	CheckMaintainer = "maintainer-mismatch"
+	CheckPackage    = "package-mismatch"
+)
+
+var (
+	urgencies = []string{"low", "medium", "high", "emergency", "critical"}
+	// reTrailer splits the trailer into the maintainer, the separator and the date
+	reTrailer = regexp.MustCompile(`^ -- (.*<[^>]*>)(\s*)(.*)$`)
+	// reTailStart matches the lines dpkg-parsechangelog stops at
+	reTailStart = regexp.MustCompile(`^(?i:old changelog:|local variables:|# |/\* |\*\*\* |vim:)`)
+)
+
+// Finding is a problem of the changelog found by Lint
+type Finding struct {
+	File     string `json:"file" yaml:"file"`
+	Line     int    `json:"line" yaml:"line"`
+	Severity string `json:"severity" yaml:"severity"`
+	Check    string `json:"check" yaml:"check"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// Findings is the result of Lint
+type Findings []*Finding
+
+func (f Findings) Header() []string {
+	return []string{"file", "line", "severity", "check", "message"}
+}
+
+func (f Findings) Rows() [][]string {
+	rows := make([][]string, 0, len(f))
+	for _, finding := range f {
+		rows = append(rows, []string{finding.File, strconv.Itoa(finding.Line), finding.Severity, finding.Check, finding.Message})
+	}
+	return rows
+}
+
+// Errors returns the number of findings with the error severity
+func (f Findings) Errors() int {
+	n := 0
+	for _, finding := range f {
+		if finding.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// linter collects the findings of a single file
+type linter struct {
+	file     string
+	findings Findings
+}
+
+func (l *linter) report(line int, severity, check, format string, args ...interface{}) {
+	l.findings = append(l.findings, &Finding{
+		File:     l.file,
+		Line:     line,
+		Severity: severity,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Lint checks the whole Debian changelog, unlike Parse it doesn't stop at the
+// first malformed line; the maintainer and the package of the latest item are
+// compared with debian/control if it exists
+func Lint(fileName string) (Findings, error) {
+	if fileName == "" {
+		fileName = changelogFileName
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	l := &linter{
+		file:     fileName,
+		findings: make(Findings, 0),
+	}
+	items, err := l.lint(file)
+	if err != nil {
+		return nil, err
+	}
+	control, err := ReadControl(controlFileName)
+	if err == nil && len(items) > 0 {
+		l.checkControl(items[0], control)
+	}
+	return l.findings, nil
+}
+
+// lint scans the changelog line by line and returns the items with valid headers
+func (l *linter) lint(r io.Reader) ([]*Item, error) {
+	items := make([]*Item, 0)
+	var item, prev *Item
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.TrimSpace(text) == "":
+			continue
+		case strings.HasPrefix(text, " --"):
+			if item == nil {
+				l.report(lineNum, SeverityError, CheckLine, "trailer line without an item header")
+				continue
+			}
+			l.checkTrailer(lineNum, text)
+			item = nil
+		case strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t"):
+			if item == nil {
+				l.report(lineNum, SeverityError, CheckLine, "change line outside of an item")
+			}
+		default:
+			header := parseHeader(text)
+			if header == nil {
+				if len(items) > 0 && reTailStart.MatchString(text) {
+					return items, scanner.Err()
+				}
+				// the lines of the malformed item are skipped up to its trailer
+				// or to the next valid header
+				if item == nil || item.Version != "" {
+					l.reportMissingTrailer(item)
+					l.report(lineNum, SeverityError, CheckHeader, "malformed item header '%s'", text)
+					item = &Item{Line: lineNum}
+				}
+				continue
+			}
+			l.reportMissingTrailer(item)
+			header.Line = lineNum
+			item = header
+			items = append(items, item)
+			l.checkHeader(item, prev)
+			prev = item
+		}
+	}
+	l.reportMissingTrailer(item)
+	return items, scanner.Err()
+}
+
+// reportMissingTrailer reports the item which isn't finished by a trailer line,
+// malformed items are already reported
+func (l *linter) reportMissingTrailer(item *Item) {
+	if item != nil && item.Version != "" {
+		l.report(item.Line, SeverityError, CheckTrailer, "item %s has no trailer line", item.Version)
+	}
+}
+
+// checkHeader checks the version and the urgency, the versions should strictly
+// decrease from the top of the file
+func (l *linter) checkHeader(item, prev *Item) {
+	version, err := ParseVersion(item.Version)
+	if err != nil {
+		l.report(item.Line, SeverityError, CheckVersion, "%s", err.Error())
+	} else if prev != nil {
+		prevVersion, err := ParseVersion(prev.Version)
+		if err == nil && version.Compare(prevVersion) >= 0 {
+			l.report(item.Line, SeverityError, CheckOrder, "version %s should be less than %s of line %d", item.Version, prev.Version, prev.Line)
+		}
+	}
+	// dch allows a comment after the urgency: "medium (HIGH for hurd)"
+	urgency := ""
+	if fields := strings.Fields(item.Urgency); len(fields) > 0 {
+		urgency = strings.ToLower(fields[0])
+	}
+	if !util.ContainsString(&urgencies, urgency) {
+		l.report(item.Line, SeverityError, CheckUrgency, "unknown urgency '%s', use one of %s", item.Urgency, strings.Join(urgencies, ", "))
+	}
+}
+
+// checkTrailer checks the separator of the maintainer and the date, and the
+// RFC 2822 date format "Day, DD Mon YYYY HH:MM:SS +ZZZZ"
+func (l *linter) checkTrailer(line int, text string) {
+	match := reTrailer.FindStringSubmatch(text)
+	if match == nil {
+		l.report(line, SeverityError, CheckLine, "malformed trailer line, expected ' -- Name <email>  date'")
+		return
+	}
+	if match[2] != "  " {
+		l.report(line, SeverityError, CheckSpacing, "maintainer and date should be separated by two spaces, found %d", len(match[2]))
+	}
+	date, err := parseRFC2822(match[3])
+	if err != nil {
+		l.report(line, SeverityError, CheckDate, "date '%s' isn't in RFC 2822 format", match[3])
+		return
+	}
+	weekday := strings.TrimSuffix(strings.Fields(match[3])[0], ",")
+	if weekday != date.Weekday().String()[:3] {
+		l.report(line, SeverityWarning, CheckWeekday, "%s is %s, not %s", date.Format("02 Jan 2006"), date.Weekday().String()[:3], weekday)
+	}
+}
+
+// parseRFC2822 parses the date of the trailer, the day of the week is required
+func parseRFC2822(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC1123Z, "Mon, 2 Jan 2006 15:04:05 -0700"} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", s)
+}
+
+// checkControl compares the latest item with the source paragraph of debian/control,
+// the maintainer should be either the maintainer or one of the uploaders
+func (l *linter) checkControl(item *Item, control *Control) {
+	if control.Package() != "" && item.Package != control.Package() {
+		l.report(item.Line, SeverityError, CheckPackage, "package %s differs from source %s of %s", item.Package, control.Package(), controlFileName)
+	}
+	if control.Source == nil || item.Maintainer == "" {
+		return
+	}
+	addr, err := mail.ParseAddress(item.Maintainer)
+	if err != nil {
+		return
+	}
+	maintainers := []string{control.Maintainer()}
+	if uploaders := control.Source.Get("Uploaders"); uploaders != "" {
+		maintainers = append(maintainers, strings.Split(uploaders, ",")...)
+	}
+	for _, maintainer := range maintainers {
+		if a, err := mail.ParseAddress(strings.TrimSpace(maintainer)); err == nil && strings.EqualFold(a.Address, addr.Address) {
+			return
+		}
+	}
+	l.report(item.Line, SeverityWarning, CheckMaintainer, "%s is neither the maintainer nor an uploader in %s", item.Maintainer, controlFileName)
+}
@@ -0,0 +1,230 @@
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	markdownFileName = "CHANGELOG.md"
+	// unreleasedVersion is the version of the section collecting unreleased changes
+	unreleasedVersion = "Unreleased"
+	markdownDate      = "2006-01-02"
+)
+
+var (
+	reSection    = regexp.MustCompile(`^##\s+(\[)?([^\]\s]+)\]?(?:\s+-\s+(\S+))?`)
+	reLinkRef    = regexp.MustCompile(`^\[[^\]]+\]:\s+\S+`)
+	reBullet     = regexp.MustCompile(`^([-*+])\s`)
+	reSubsection = regexp.MustCompile(`^###\s+(\S+)`)
+)
+
+// MarkdownChangelog is a CHANGELOG.md in the Keep a Changelog style:
+// "## [version] - date" sections with "### Category" subsections
+type MarkdownChangelog struct {
+	Items []*Item
+	// head contains the title and the description before the first section
+	head string
+	// tail contains the link reference definitions after the sections
+	tail string
+	// brackets and bullet keep the style of the document for the new items
+	brackets bool
+	bullet   string
+}
+
+// ParseMarkdown reads the sections of the Markdown changelog
+func ParseMarkdown(r io.Reader) (*MarkdownChangelog, error) {
+	changelog := &MarkdownChangelog{
+		Items:  make([]*Item, 0),
+		bullet: "-",
+	}
+	reader := bufio.NewReader(r)
+	var item *Item
+	bulletFound := false
+	finishItem := func() {
+		if item != nil {
+			item.Changes = strings.Trim(item.raw[strings.Index(item.raw, "\n")+1:], "\n")
+			item.rendered = changelog.render(item)
+		}
+	}
+	lineNum := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		lineNum++
+		text := strings.TrimRight(line, "\r\n")
+		if match := reSection.FindStringSubmatch(text); match != nil {
+			finishItem()
+			item = &Item{
+				Version: match[2],
+				Date:    match[3],
+				Line:    lineNum,
+				raw:     line,
+			}
+			if match[1] != "" && !strings.EqualFold(match[2], unreleasedVersion) {
+				changelog.brackets = true
+			}
+			changelog.Items = append(changelog.Items, item)
+			continue
+		}
+		if item == nil {
+			changelog.head += line
+			continue
+		}
+		if reLinkRef.MatchString(text) || changelog.tail != "" {
+			changelog.tail += line
+			continue
+		}
+		if match := reBullet.FindStringSubmatch(text); match != nil && !bulletFound {
+			changelog.bullet = match[1]
+			bulletFound = true
+		}
+		item.raw += line
+	}
+	finishItem()
+	return changelog, nil
+}
+
+// Entries implements Document
+func (c *MarkdownChangelog) Entries() []*Item {
+	return c.Items
+}
+
+// Add implements Document, the new item goes after the Unreleased section and
+// takes its changes over, since they are released now
+func (c *MarkdownChangelog) Add(item *Item) {
+	idx := 0
+	if len(c.Items) > 0 && strings.EqualFold(c.Items[0].Version, unreleasedVersion) {
+		unreleased := c.Items[0]
+		if unreleased.Changes != "" {
+			item.Changes = mergeSections(unreleased.Changes, item.Changes)
+			unreleased.Changes = ""
+		}
+		idx = 1
+	}
+	items := make([]*Item, 0, len(c.Items)+1)
+	items = append(items, c.Items[:idx]...)
+	items = append(items, item)
+	c.Items = append(items, c.Items[idx:]...)
+}
+
+// RenderChanges implements Document, changes are grouped by categories
+func (c *MarkdownChangelog) RenderChanges(changes []Change) string {
+	groups := make([]string, 0, len(categories))
+	for _, category := range categories {
+		lines := make([]string, 0)
+		for _, change := range changes {
+			if change.Category == category || change.Category == "" && category == CategoryChanged {
+				lines = append(lines, c.bullet+" "+change.Text)
+			}
+		}
+		if len(lines) > 0 {
+			groups = append(groups, "### "+category+"\n"+strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(groups, "\n\n")
+}
+
+// WriteTo implements Document, unchanged sections are written exactly as they were read
+func (c *MarkdownChangelog) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(c.head)
+	for _, item := range c.Items {
+		buf.WriteString(item.text(c.render))
+	}
+	buf.WriteString(c.tail)
+	return buf.WriteTo(w)
+}
+
+func (c *MarkdownChangelog) render(i *Item) string {
+	b := &strings.Builder{}
+	if c.brackets || strings.EqualFold(i.Version, unreleasedVersion) {
+		fmt.Fprintf(b, "## [%s]", i.Version)
+	} else {
+		fmt.Fprintf(b, "## %s", i.Version)
+	}
+	if i.Date != "" {
+		fmt.Fprintf(b, " - %s", i.Date)
+	}
+	b.WriteString("\n")
+	if i.Changes != "" {
+		fmt.Fprintf(b, "%s\n", i.Changes)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// mergeSections merges the changes grouped by "### Category" subsections
+func mergeSections(a, b string) string {
+	order := make([]string, 0)
+	sections := make(map[string][]string)
+	for _, text := range []string{a, b} {
+		category := ""
+		for _, line := range strings.Split(text, "\n") {
+			if match := reSubsection.FindStringSubmatch(line); match != nil {
+				category = match[1]
+				if _, ok := sections[category]; !ok {
+					order = append(order, category)
+					sections[category] = make([]string, 0)
+				}
+				continue
+			}
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if _, ok := sections[category]; !ok {
+				order = append(order, category)
+			}
+			sections[category] = append(sections[category], line)
+		}
+	}
+	groups := make([]string, 0, len(order))
+	for _, category := range order {
+		lines := sections[category]
+		if category != "" {
+			lines = append([]string{"### " + category}, lines...)
+		}
+		groups = append(groups, strings.Join(lines, "\n"))
+	}
+	return strings.Join(groups, "\n\n")
+}
+
+// markdownFormat is the CHANGELOG.md format
+type markdownFormat struct{}
+
+func (f *markdownFormat) Name() string {
+	return FormatMarkdown
+}
+
+func (f *markdownFormat) FileName() string {
+	return markdownFileName
+}
+
+func (f *markdownFormat) Parse(r io.Reader) (Document, error) {
+	return ParseMarkdown(r)
+}
+
+func (f *markdownFormat) Complete(newItem *Item, prevItem *Item) error {
+	if newItem.Date == "" {
+		newItem.Date = f.FormatDate(time.Now())
+	}
+	return nil
+}
+
+func (f *markdownFormat) FormatDate(t time.Time) string {
+	return t.Format(markdownDate)
+}
+
+func (f *markdownFormat) ParseDate(s string) (time.Time, error) {
+	return time.Parse(markdownDate, s)
+}
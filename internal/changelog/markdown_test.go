@@ -0,0 +1,131 @@
+package changelog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readMarkdown(t *testing.T, file string) (*MarkdownChangelog, []byte) {
+	t.Helper()
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseMarkdown(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed, golden
+}
+
+func TestMarkdownRoundTrip(t *testing.T) {
+	tests := []struct {
+		file     string
+		versions []string
+		dates    []string
+	}{
+		{
+			file:     "gitlab-tools.md",
+			versions: []string{"0.0.1"},
+			dates:    []string{"2019-10-08"},
+		},
+		{
+			file:     "keepachangelog.md",
+			versions: []string{"Unreleased", "1.1.0", "1.0.0", "0.0.1"},
+			dates:    []string{"", "2019-02-15", "2017-06-20", "2014-05-31"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			parsed, golden := readMarkdown(t, tt.file)
+			versions := make([]string, 0, len(parsed.Items))
+			dates := make([]string, 0, len(parsed.Items))
+			for _, item := range parsed.Items {
+				versions = append(versions, item.Version)
+				dates = append(dates, item.Date)
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("versions are %v, want %v", versions, tt.versions)
+			}
+			if !reflect.DeepEqual(dates, tt.dates) {
+				t.Errorf("dates are %q, want %q", dates, tt.dates)
+			}
+
+			buf := &bytes.Buffer{}
+			_, err := parsed.WriteTo(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), golden) {
+				t.Errorf("written changelog differs from %s:\n%s", tt.file, buf.String())
+			}
+		})
+	}
+}
+
+func TestMarkdownLinkReferences(t *testing.T) {
+	parsed, _ := readMarkdown(t, "keepachangelog.md")
+	if parsed.tail == "" || parsed.tail[0] != '[' {
+		t.Errorf("link references aren't kept in the tail: %q", parsed.tail)
+	}
+	if !parsed.brackets || parsed.bullet != "-" {
+		t.Errorf("style is brackets %v, bullet %q", parsed.brackets, parsed.bullet)
+	}
+	last := parsed.Items[len(parsed.Items)-1]
+	want := "### Added\n- This CHANGELOG file to hopefully serve as an evolving example of a\n  standardized open source project CHANGELOG."
+	if last.Changes != want {
+		t.Errorf("changes of the last section are %q, want %q", last.Changes, want)
+	}
+}
+
+func TestMarkdownAddReleasesUnreleased(t *testing.T) {
+	parsed, _ := readMarkdown(t, "keepachangelog.md")
+	item := &Item{
+		Version: "1.2.0",
+		Date:    "2019-03-01",
+		Changes: parsed.RenderChanges([]Change{
+			{Category: CategoryFixed, Text: "Crash on start."},
+			{Category: CategoryRemoved, Text: "Unused translations."},
+		}),
+	}
+	parsed.Add(item)
+
+	buf := &bytes.Buffer{}
+	_, err := parsed.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "keepachangelog-added.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("written changelog differs from keepachangelog-added.md:\n%s", buf.String())
+	}
+	if latest := latestItem(parsed); latest != item {
+		t.Errorf("latest item is %s, want the added one", latest.Version)
+	}
+}
+
+func TestShowSkipsUnreleased(t *testing.T) {
+	_, golden := readMarkdown(t, "keepachangelog.md")
+	convertFixture(t, map[string]string{markdownFileName: string(golden)})
+
+	entry, err := Show(FormatMarkdown, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Version != "1.1.0" {
+		t.Errorf("latest version is %s, want 1.1.0", entry.Version)
+	}
+	entry, err = Show(FormatMarkdown, "unreleased")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Version != unreleasedVersion {
+		t.Errorf("version is %s, want %s", entry.Version, unreleasedVersion)
+	}
+}
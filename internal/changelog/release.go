@@ -0,0 +1,226 @@
+package changelog
+
+import (
+	"fmt"
+	"net/mail"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	unreleased          = "UNRELEASED"
+	defaultDistribution = "unstable"
+)
+
+// ReleaseOptions represents the options of finalizing the top changelog item
+type ReleaseOptions struct {
+	// Distribution replaces UNRELEASED, the latest released distribution
+	// (or unstable) is used if it's empty
+	Distribution string
+	// Maintainer is taken from DEBFULLNAME/DEBEMAIL or git config if it's empty
+	Maintainer string
+	Date       string
+	// Tag commits the changelog and tags the commit with TagPrefix + version,
+	// nothing is pushed
+	Tag       bool
+	TagPrefix string
+	// DryRun prints the diff of the changelog instead of writing and tagging it
+	DryRun bool
+}
+
+// Release finalizes the top UNRELEASED changelog item like dch --release does
+func Release(opt *ReleaseOptions) error {
+	changelog, err := os.Open(changelogFileName)
+	if err != nil {
+		return err
+	}
+	parsed, err := Parse(changelog)
+	_ = changelog.Close()
+	if err != nil {
+		return err
+	}
+	if len(parsed.Items) == 0 {
+		return fmt.Errorf("error: %s has no items", changelogFileName)
+	}
+	item := parsed.Items[0]
+	if item.Release != unreleased {
+		return fmt.Errorf("error: %s (%s) is already released to %s", item.Package, item.Version, item.Release)
+	}
+
+	item.Release = opt.Distribution
+	if item.Release == "" {
+		item.Release = releasedDistribution(parsed.Items[1:])
+	}
+	item.Maintainer = opt.Maintainer
+	if item.Maintainer == "" {
+		item.Maintainer = currentMaintainer(parsed.Items[0].Maintainer)
+	}
+	item.Date = opt.Date
+	if item.Date == "" {
+		item.Date, err = determineDate(item)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the release commit contains the changelog only
+	if opt.Tag && !opt.DryRun {
+		err = checkStaged(changelogFileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeChangelog(changelogFileName, parsed, opt.DryRun)
+	if err != nil || opt.DryRun {
+		return err
+	}
+	fmt.Printf("released %s (%s) to %s\n", item.Package, item.Version, item.Release)
+	if !opt.Tag {
+		return nil
+	}
+	return tagRelease(item, opt.TagPrefix+item.Version)
+}
+
+// releasedDistribution returns the distribution of the latest released item
+func releasedDistribution(items []*Item) string {
+	for _, item := range items {
+		if item.Release != unreleased && item.Release != "" {
+			return item.Release
+		}
+	}
+	return defaultDistribution
+}
+
+// currentMaintainer returns the maintainer as dch determines it: from
+// DEBFULLNAME (or NAME) and DEBEMAIL (or EMAIL), then from git config
+func currentMaintainer(fallback string) string {
+	name := firstEnv("DEBFULLNAME", "NAME")
+	email := firstEnv("DEBEMAIL", "EMAIL")
+	if addr, err := mail.ParseAddress(email); err == nil && addr.Name != "" {
+		// DEBEMAIL may contain both: "Full Name <email>"
+		name, email = addr.Name, addr.Address
+	}
+	if name == "" || email == "" {
+		if user := gitUser(); user != nil {
+			if name == "" {
+				name = user.Name
+			}
+			if email == "" {
+				email = user.Email
+			}
+		}
+	}
+	if name == "" || email == "" {
+		return fallback
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// gitUser returns the user of the repository in the current directory,
+// the global git config is used outside of a repository
+func gitUser() *object.Signature {
+	var cfg *gitconfig.Config
+	repo, err := git.PlainOpen(".")
+	if err == nil {
+		cfg, err = repo.ConfigScoped(gitconfig.SystemScope)
+	} else {
+		cfg, err = gitconfig.LoadConfig(gitconfig.GlobalScope)
+	}
+	if err != nil || cfg.User.Name == "" && cfg.User.Email == "" {
+		return nil
+	}
+	return &object.Signature{
+		Name:  cfg.User.Name,
+		Email: cfg.User.Email,
+		When:  time.Now(),
+	}
+}
+
+// checkStaged fails if changes other than the file are staged, the commit
+// would contain them along with the file
+func checkStaged(fileName string) error {
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	staged := make([]string, 0)
+	for path, fileStatus := range status {
+		if path != fileName && fileStatus.Staging != git.Unmodified && fileStatus.Staging != git.Untracked {
+			staged = append(staged, path)
+		}
+	}
+	if len(staged) > 0 {
+		sort.Strings(staged)
+		return fmt.Errorf("error: other changes are staged, commit or unstage them first: %s", strings.Join(staged, ", "))
+	}
+	return nil
+}
+
+// tagRelease commits the changelog and creates an annotated tag of the commit
+func tagRelease(item *Item, tagName string) error {
+	repo, err := git.PlainOpen(".")
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	_, err = worktree.Add(changelogFileName)
+	if err != nil {
+		return err
+	}
+	signature := maintainerSignature(item.Maintainer)
+	message := fmt.Sprintf("Release %s %s", item.Package, item.Version)
+	hash, err := worktree.Commit(message, &git.CommitOptions{
+		Author: signature,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = repo.CreateTag(tagName, hash, &git.CreateTagOptions{
+		Tagger:  signature,
+		Message: fmt.Sprintf("%s %s (%s)", item.Package, item.Version, item.Release),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("tagged %s as %s\n", hash, tagName)
+	return nil
+}
+
+func maintainerSignature(maintainer string) *object.Signature {
+	signature := &object.Signature{
+		Name: maintainer,
+		When: time.Now(),
+	}
+	if addr, err := mail.ParseAddress(maintainer); err == nil {
+		signature.Name = addr.Name
+		signature.Email = addr.Address
+	}
+	return signature
+}
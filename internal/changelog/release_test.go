@@ -0,0 +1,176 @@
+package changelog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const unreleasedChangelog = `foo (1.0-3) UNRELEASED; urgency=medium
+
+  * Fix the build.
+
+ -- Jane Doe <jane@example.com>  Mon, 01 Mar 2021 10:00:00 +0000
+
+foo (1.0-2) bookworm; urgency=medium
+
+  * Initial release.
+
+ -- Jane Doe <jane@example.com>  Sun, 28 Feb 2021 10:00:00 +0000
+`
+
+// releaseFixture creates a repository with the committed changelog in a
+// temporary directory and makes it the working directory
+func releaseFixture(t *testing.T) (*git.Repository, *git.Worktree) {
+	dir, err := ioutil.TempDir("", "changelog-release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+		_ = os.RemoveAll(dir)
+	})
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := git.PlainInit(".", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll("debian", 0755)
+	if err == nil {
+		err = ioutil.WriteFile(changelogFileName, []byte(unreleasedChangelog), 0644)
+	}
+	if err == nil {
+		_, err = worktree.Add(changelogFileName)
+	}
+	if err == nil {
+		_, err = worktree.Commit("Initial commit", &git.CommitOptions{
+			Author: &object.Signature{Name: "Jane Doe", Email: "jane@example.com", When: time.Now()},
+		})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, worktree
+}
+
+func TestReleaseTag(t *testing.T) {
+	repo, _ := releaseFixture(t)
+	// an untracked file must not get into the release commit
+	err := ioutil.WriteFile("other.txt", []byte("other\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Release(&ReleaseOptions{
+		Maintainer: "John Roe <john@example.com>",
+		Date:       "Tue, 02 Mar 2021 12:30:00 +0100",
+		Tag:        true,
+		TagPrefix:  "debian/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(changelogFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := string(b)
+	want := strings.Replace(unreleasedChangelog, "UNRELEASED", "bookworm", 1)
+	want = strings.Replace(want, " -- Jane Doe <jane@example.com>  Mon, 01 Mar 2021 10:00:00 +0000",
+		" -- John Roe <john@example.com>  Tue, 02 Mar 2021 12:30:00 +0100", 1)
+	if released != want {
+		t.Errorf("released changelog:\n%s\nwant:\n%s", released, want)
+	}
+
+	ref, err := repo.Tag("debian/1.0-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := tag.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Hash != head.Hash() {
+		t.Errorf("tag points to %s, want HEAD %s", commit.Hash, head.Hash())
+	}
+	if commit.Message != "Release foo 1.0-3" || commit.Author.Email != "john@example.com" {
+		t.Errorf("unexpected commit %q by %s", commit.Message, commit.Author.Email)
+	}
+	stats, err := commit.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || stats[0].Name != changelogFileName {
+		t.Errorf("release commit changes %v, want %s only", stats, changelogFileName)
+	}
+	file, err := commit.File(changelogFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if committed != released {
+		t.Errorf("committed changelog differs from the released one:\n%s", committed)
+	}
+}
+
+func TestReleaseTagStaged(t *testing.T) {
+	repo, worktree := releaseFixture(t)
+	err := ioutil.WriteFile("other.txt", []byte("other\n"), 0644)
+	if err == nil {
+		_, err = worktree.Add("other.txt")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Release(&ReleaseOptions{Tag: true, Maintainer: "John Roe <john@example.com>"})
+	if err == nil || !strings.Contains(err.Error(), "other.txt") {
+		t.Fatalf("release with other staged changes returned %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join("debian", "changelog"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != unreleasedChangelog {
+		t.Errorf("changelog was changed:\n%s", b)
+	}
+	tags, err := repo.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = tags.ForEach(func(ref *plumbing.Reference) error {
+		t.Errorf("unexpected tag %s", ref.Name())
+		return nil
+	})
+}
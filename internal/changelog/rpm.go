@@ -0,0 +1,229 @@
+package changelog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	specFilePattern = "*.spec"
+	rpmDate         = "Mon Jan 02 2006"
+)
+
+var (
+	reSpecHeader = regexp.MustCompile(`^\*\s+(\S+\s+\S+\s+\d{1,2}\s+\d{4})\s+(.*)$`)
+	reSpecName   = regexp.MustCompile(`^Name:\s*(\S+)`)
+	reSpecTag    = regexp.MustCompile(`^%[a-zA-Z_]`)
+)
+
+// SpecFile contains the %changelog items of an RPM spec file
+type SpecFile struct {
+	Items []*Item
+	// Name is the package name of the spec file
+	Name string
+	// head contains the spec file up to the first item, tail contains the
+	// sections after %changelog
+	head string
+	tail string
+	// section is false if the spec file has no %changelog section yet
+	section bool
+}
+
+// ParseSpec reads the %changelog items of an RPM spec file,
+// every item header is "* Day Mon DD YYYY Name <email> - version-release"
+func ParseSpec(r io.Reader) (*SpecFile, error) {
+	spec := &SpecFile{
+		Items: make([]*Item, 0),
+	}
+	reader := bufio.NewReader(r)
+	var item *Item
+	// blank lines belong to the item if more changes follow, otherwise they separate items
+	blank := ""
+	finishItem := func() {
+		if item != nil {
+			item.Changes = strings.Trim(item.raw[strings.Index(item.raw, "\n")+1:], "\n")
+			item.rendered = renderRPM(item)
+			item.sep = blank
+			blank = ""
+		}
+	}
+	lineNum := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		lineNum++
+		text := strings.TrimRight(line, "\r\n")
+		switch {
+		case !spec.section:
+			spec.head += line
+			if match := reSpecName.FindStringSubmatch(text); match != nil && spec.Name == "" {
+				spec.Name = match[1]
+			}
+			if strings.TrimSpace(text) == "%changelog" {
+				spec.section = true
+			}
+		case spec.tail != "" || reSpecTag.MatchString(text):
+			// the rest of the file is another section
+			if spec.tail == "" {
+				finishItem()
+				spec.tail = blank
+				blank = ""
+			}
+			spec.tail += line
+		case strings.TrimSpace(text) == "":
+			if item == nil {
+				spec.head += line
+			} else {
+				blank += line
+			}
+		case reSpecHeader.MatchString(text):
+			finishItem()
+			item = parseSpecHeader(text)
+			item.Package = spec.Name
+			item.Line = lineNum
+			item.raw = line
+			spec.Items = append(spec.Items, item)
+		case item == nil:
+			return nil, &ParseError{Line: lineNum, Msg: "malformed %changelog item header"}
+		default:
+			item.raw += blank + line
+			blank = ""
+		}
+	}
+	if spec.tail == "" {
+		finishItem()
+	}
+	return spec, nil
+}
+
+// parseSpecHeader splits the item header into the date, the maintainer and
+// the version, the version follows the maintainer email and an optional dash
+func parseSpecHeader(text string) *Item {
+	match := reSpecHeader.FindStringSubmatch(text)
+	item := &Item{
+		Date: match[1],
+	}
+	rest := match[2]
+	idx := strings.LastIndex(rest, ">")
+	if idx < 0 {
+		idx = strings.LastIndex(rest, " - ")
+	}
+	if idx < 0 {
+		item.Maintainer = rest
+		return item
+	}
+	item.Maintainer = strings.TrimSpace(rest[:idx+1])
+	version := strings.TrimSpace(rest[idx+1:])
+	item.Version = strings.TrimSpace(strings.TrimPrefix(version, "-"))
+	return item
+}
+
+// Entries implements Document
+func (s *SpecFile) Entries() []*Item {
+	return s.Items
+}
+
+// Add implements Document
+func (s *SpecFile) Add(item *Item) {
+	s.Items = append([]*Item{item}, s.Items...)
+}
+
+// RenderChanges implements Document, every change is a "- " bullet
+func (s *SpecFile) RenderChanges(changes []Change) string {
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		lines = append(lines, "- "+change.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteTo implements Document, unchanged items are written exactly as they were read
+func (s *SpecFile) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(s.head)
+	if !s.section && len(s.Items) > 0 {
+		if s.head != "" {
+			if !strings.HasSuffix(s.head, "\n") {
+				buf.WriteString("\n")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString("%changelog\n")
+	}
+	for i, item := range s.Items {
+		buf.WriteString(item.text(renderRPM))
+		if item.sep == "" && item.raw == "" && (i < len(s.Items)-1 || s.tail != "") {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(item.sep)
+	}
+	buf.WriteString(s.tail)
+	return buf.WriteTo(w)
+}
+
+func renderRPM(i *Item) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "* %s %s - %s\n", i.Date, i.Maintainer, i.Version)
+	if i.Changes != "" {
+		fmt.Fprintf(b, "%s\n", i.Changes)
+	}
+	return b.String()
+}
+
+// rpmFormat is the %changelog section of the spec file in the current directory
+type rpmFormat struct{}
+
+func (f *rpmFormat) Name() string {
+	return FormatRPM
+}
+
+// FileName returns the first spec file in the current directory
+func (f *rpmFormat) FileName() string {
+	matches, err := filepath.Glob(specFilePattern)
+	if err != nil || len(matches) == 0 {
+		return specFilePattern
+	}
+	return matches[0]
+}
+
+func (f *rpmFormat) Parse(r io.Reader) (Document, error) {
+	return ParseSpec(r)
+}
+
+func (f *rpmFormat) Complete(newItem *Item, prevItem *Item) error {
+	if newItem.Maintainer == "" {
+		fallback := ""
+		if prevItem != nil {
+			fallback = prevItem.Maintainer
+		}
+		newItem.Maintainer = currentMaintainer(fallback)
+		if newItem.Maintainer == "" {
+			return fmt.Errorf("error: could not determine the maintainer, set DEBFULLNAME and DEBEMAIL or git user")
+		}
+	}
+	if newItem.Date == "" {
+		newItem.Date = f.FormatDate(time.Now())
+	}
+	return nil
+}
+
+func (f *rpmFormat) FormatDate(t time.Time) string {
+	return t.Format(rpmDate)
+}
+
+// ParseDate parses the date of the %changelog entry header, rpmbuild
+// accepts "Mon Jan 2 2006" as well as "Mon Jan 02 2006"
+func (f *rpmFormat) ParseDate(s string) (time.Time, error) {
+	return time.Parse("Mon Jan 2 2006", strings.Join(strings.Fields(s), " "))
+}
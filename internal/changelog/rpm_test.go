@@ -0,0 +1,138 @@
+package changelog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSpecRoundTrip(t *testing.T) {
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "sed.spec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := ParseSpec(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "sed" {
+		t.Errorf("name is %q, want sed", spec.Name)
+	}
+	want := []Item{
+		{Package: "sed", Version: "4.9-1", Maintainer: "Jakub Martisko <jamartis@redhat.com>", Date: "Mon Jan 09 2023", Line: 52},
+		{Package: "sed", Version: "4.8-11", Maintainer: "Fedora Release Engineering <releng@fedoraproject.org>", Date: "Thu Jul 21 2022", Line: 57},
+		{Package: "sed", Version: "4.8-10", Maintainer: "Jakub Martisko <jamartis@redhat.com>", Date: "Wed Jan 19 2022", Line: 60},
+		{Package: "sed", Version: "4.8-7", Maintainer: "Jakub Martisko <jamartis@redhat.com>", Date: "Tue Feb 2 2021", Line: 65},
+		{Package: "sed", Version: "4.8-1", Maintainer: "Jakub Martisko <jamartis@redhat.com>", Date: "Wed Jan 15 2020", Line: 68},
+	}
+	got := make([]Item, 0, len(spec.Items))
+	for _, item := range spec.Items {
+		got = append(got, Item{Package: item.Package, Version: item.Version, Maintainer: item.Maintainer, Date: item.Date, Line: item.Line})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("items are %+v, want %+v", got, want)
+	}
+	changes := "- Fix the testsuite on ppc64le\n\n- Use the %%autosetup macro"
+	if spec.Items[2].Changes != changes {
+		t.Errorf("changes of 4.8-10 are %q, want %q", spec.Items[2].Changes, changes)
+	}
+
+	buf := &bytes.Buffer{}
+	_, err = spec.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), golden) {
+		t.Errorf("written spec file differs from sed.spec:\n%s", buf.String())
+	}
+}
+
+// convertFixture writes the files into a temporary directory and makes it
+// the working directory
+func convertFixture(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = os.Chdir(wd)
+	})
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertDebianToRPM(t *testing.T) {
+	convertFixture(t, map[string]string{
+		changelogFileName: unreleasedChangelog,
+		"foo.spec":        "Name: foo\nVersion: 1.0\nRelease: 3\n",
+	})
+	buf := &bytes.Buffer{}
+	err := Convert(buf, &ConvertOptions{To: FormatRPM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Name: foo
+Version: 1.0
+Release: 3
+
+%changelog
+* Mon Mar 01 2021 Jane Doe <jane@example.com> - 1.0-3
+- Fix the build.
+
+* Sun Feb 28 2021 Jane Doe <jane@example.com> - 1.0-2
+- Initial release.
+`
+	if buf.String() != want {
+		t.Errorf("converted spec file is\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestConvertRPMToDebian(t *testing.T) {
+	spec, err := ioutil.ReadFile(filepath.Join("testdata", "sed.spec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	convertFixture(t, map[string]string{"sed.spec": string(spec)})
+	buf := &bytes.Buffer{}
+	err = Convert(buf, &ConvertOptions{From: FormatRPM, To: FormatDebian})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("%v:\n%s", err, buf.String())
+	}
+	if len(parsed.Items) != 5 {
+		t.Fatalf("converted %d items, want 5:\n%s", len(parsed.Items), buf.String())
+	}
+	want := `sed (4.9-1) unstable; urgency=medium
+
+  * Rebase to sed 4.9 Resolves: rhbz#2158838
+  * Drop the patches merged upstream
+
+ -- Jakub Martisko <jamartis@redhat.com>  Mon, 09 Jan 2023 00:00:00 +0000
+`
+	if first := parsed.Items[0].text(renderDebian); first != want {
+		t.Errorf("first converted item is\n%s\nwant\n%s", first, want)
+	}
+	if date := parsed.Items[3].Date; date != "Tue, 02 Feb 2021 00:00:00 +0000" {
+		t.Errorf("date of the single digit day is %q", date)
+	}
+}
@@ -0,0 +1,127 @@
+package changelog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Entry is a changelog item reported by Show and List
+type Entry struct {
+	Package       string   `json:"package,omitempty" yaml:"package,omitempty"`
+	Version       string   `json:"version" yaml:"version"`
+	Date          string   `json:"date" yaml:"date"`
+	Distributions []string `json:"distributions,omitempty" yaml:"distributions,omitempty"`
+	Urgency       string   `json:"urgency,omitempty" yaml:"urgency,omitempty"`
+	Maintainer    string   `json:"maintainer,omitempty" yaml:"maintainer,omitempty"`
+	Changes       []Change `json:"changes" yaml:"changes"`
+	// Text is the item as it's written in the changelog
+	Text string `json:"text" yaml:"text"`
+}
+
+// Entries is the result of List
+type Entries []*Entry
+
+var entryHeader = []string{"package", "version", "date", "distributions", "urgency"}
+
+func (e *Entry) Header() []string {
+	return entryHeader
+}
+
+func (e *Entry) Rows() [][]string {
+	return [][]string{e.row()}
+}
+
+func (e *Entry) row() []string {
+	return []string{e.Package, e.Version, e.Date, strings.Join(e.Distributions, " "), e.Urgency}
+}
+
+func (e Entries) Header() []string {
+	return entryHeader
+}
+
+func (e Entries) Rows() [][]string {
+	rows := make([][]string, 0, len(e))
+	for _, entry := range e {
+		rows = append(rows, entry.row())
+	}
+	return rows
+}
+
+func newEntry(item *Item) *Entry {
+	text := item.raw
+	if text == "" {
+		text = item.rendered
+	}
+	return &Entry{
+		Package:       item.Package,
+		Version:       item.Version,
+		Date:          item.Date,
+		Distributions: item.Distributions(),
+		Urgency:       item.Urgency,
+		Maintainer:    item.Maintainer,
+		Changes:       parseChanges(item.Changes),
+		Text:          text,
+	}
+}
+
+// Show returns the changelog item of the version, the latest item if the
+// version is empty, it's the one Add takes the next version from
+func Show(formatName string, version string) (*Entry, error) {
+	format, err := detectFormat(formatName)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := readDocument(format, false)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		item := latestItem(doc)
+		if item == nil {
+			return nil, fmt.Errorf("error: no version found in %s", format.FileName())
+		}
+		return newEntry(item), nil
+	}
+	idx := versionIndex(doc, version)
+	if idx < 0 {
+		return nil, fmt.Errorf("error: version %s not found in %s", version, format.FileName())
+	}
+	item, err := readChangelogItem(doc, idx)
+	if err != nil {
+		return nil, err
+	}
+	return newEntry(item), nil
+}
+
+// List returns all the changelog items, the latest first
+func List(formatName string) (Entries, error) {
+	format, err := detectFormat(formatName)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := readDocument(format, false)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(Entries, 0, len(doc.Entries()))
+	for _, item := range doc.Entries() {
+		entries = append(entries, newEntry(item))
+	}
+	return entries, nil
+}
+
+// versionIndex returns the index of the item with the version or -1, the
+// versions are compared as Debian versions unless they match literally, so 1.01 matches 1.1
+func versionIndex(doc Document, version string) int {
+	for idx, item := range doc.Entries() {
+		if strings.EqualFold(item.Version, version) {
+			return idx
+		}
+	}
+	for idx, item := range doc.Entries() {
+		if c, err := CompareVersions(item.Version, version); err == nil && c == 0 {
+			return idx
+		}
+	}
+	return -1
+}
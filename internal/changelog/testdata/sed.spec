@@ -0,0 +1,70 @@
+%global _hardened_build 1
+
+Summary: A GNU stream text editor
+Name: sed
+Version: 4.9
+Release: 1%{?dist}
+License: GPLv3+
+URL: https://www.gnu.org/software/sed/
+Source0: https://ftp.gnu.org/pub/gnu/sed/sed-%{version}.tar.xz
+Source1: http://sed.sourceforge.net/grabbag/ss/sedfaq.txt.gz
+
+BuildRequires: make
+BuildRequires: gcc
+BuildRequires: glibc-devel
+BuildRequires: libselinux-devel
+BuildRequires: libacl-devel
+BuildRequires: perl-Getopt-Long
+Provides: /bin/sed
+
+%description
+The sed (Stream EDitor) editor is a stream or batch (non-interactive)
+editor.  Sed takes text as input, performs an operation or set of
+operations on the text and outputs the modified text.  The operations
+that sed performs (substitutions, deletions, insertions, etc.) can be
+specified in a script file or from the command line.
+
+%prep
+%autosetup -p1
+
+%build
+%configure --without-included-regex
+%make_build
+install -m 644 -p %{SOURCE1} sedfaq.txt.gz
+
+%check
+make check
+
+%install
+%make_install
+rm -f ${RPM_BUILD_ROOT}/%{_infodir}/dir
+
+%find_lang %{name}
+
+%files -f %{name}.lang
+%license COPYING
+%doc BUGS NEWS THANKS README AUTHORS sedfaq.txt.gz
+%{_bindir}/sed
+%{_infodir}/sed.info*
+%{_mandir}/man1/sed.1*
+
+%changelog
+* Mon Jan 09 2023 Jakub Martisko <jamartis@redhat.com> - 4.9-1
+- Rebase to sed 4.9
+  Resolves: rhbz#2158838
+- Drop the patches merged upstream
+
+* Thu Jul 21 2022 Fedora Release Engineering <releng@fedoraproject.org> - 4.8-11
+- Rebuilt for https://fedoraproject.org/wiki/Fedora_37_Mass_Rebuild
+
+* Wed Jan 19 2022 Jakub Martisko <jamartis@redhat.com> 4.8-10
+- Fix the testsuite on ppc64le
+
+- Use the %%autosetup macro
+
+* Tue Feb 2 2021 Jakub Martisko <jamartis@redhat.com> - 4.8-7
+- Add gating tests
+
+* Wed Jan 15 2020 Jakub Martisko <jamartis@redhat.com> - 4.8-1
+- Rebase to 4.8
+- Fixes for the upstream testsuite
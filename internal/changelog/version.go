@@ -0,0 +1,262 @@
+package changelog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version parts to bump
+const (
+	BumpMajor    = "major"
+	BumpMinor    = "minor"
+	BumpPatch    = "patch"
+	BumpRevision = "revision"
+)
+
+// Version is a Debian package version: [epoch:]upstream_version[-debian_revision]
+type Version struct {
+	Epoch    int
+	Upstream string
+	// Revision is empty for native packages
+	Revision string
+}
+
+// ParseVersion parses the version according to the Debian policy
+func ParseVersion(s string) (*Version, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+	v := &Version{}
+	if idx := strings.Index(s, ":"); idx >= 0 {
+		epoch, err := strconv.Atoi(s[:idx])
+		if err != nil || epoch < 0 {
+			return nil, fmt.Errorf("version '%s': epoch should be a non-negative number", s)
+		}
+		v.Epoch = epoch
+		s = s[idx+1:]
+	}
+	v.Upstream = s
+	if idx := strings.LastIndex(s, "-"); idx >= 0 {
+		v.Upstream = s[:idx]
+		v.Revision = s[idx+1:]
+		if v.Revision == "" || strings.Trim(v.Revision, "+.~"+alnum) != "" {
+			return nil, fmt.Errorf("version '%s': invalid revision '%s'", s, v.Revision)
+		}
+	}
+	if v.Upstream == "" || v.Upstream[0] < '0' || v.Upstream[0] > '9' {
+		return nil, fmt.Errorf("version '%s': upstream version should start with a digit", s)
+	}
+	if strings.Trim(v.Upstream, "+.~-:"+alnum) != "" {
+		return nil, fmt.Errorf("version '%s': invalid upstream version '%s'", s, v.Upstream)
+	}
+	return v, nil
+}
+
+const alnum = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+func (v *Version) String() string {
+	s := v.Upstream
+	if v.Epoch > 0 {
+		s = strconv.Itoa(v.Epoch) + ":" + s
+	}
+	if v.Revision != "" {
+		s += "-" + v.Revision
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if the version is less than, equal to or greater than the other one
+func (v *Version) Compare(other *Version) int {
+	switch {
+	case v.Epoch < other.Epoch:
+		return -1
+	case v.Epoch > other.Epoch:
+		return 1
+	}
+	if c := compareString(v.Upstream, other.Upstream); c != 0 {
+		return c
+	}
+	return compareString(v.Revision, other.Revision)
+}
+
+// CompareVersions parses and compares two versions
+func CompareVersions(a, b string) (int, error) {
+	va, err := ParseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := ParseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// compareString compares the upstream versions or revisions, the non-digit
+// parts are compared lexically with letters sorting before non-letters and
+// the tilde sorting before anything, the digit parts are compared numerically
+func compareString(a, b string) int {
+	for a != "" || b != "" {
+		var pa, pb string
+		pa, a = splitPrefix(a, false)
+		pb, b = splitPrefix(b, false)
+		for i := 0; i < len(pa) || i < len(pb); i++ {
+			oa, ob := order(pa, i), order(pb, i)
+			if oa != ob {
+				return sign(oa - ob)
+			}
+		}
+		pa, a = splitPrefix(a, true)
+		pb, b = splitPrefix(b, true)
+		pa, pb = strings.TrimLeft(pa, "0"), strings.TrimLeft(pb, "0")
+		if len(pa) != len(pb) {
+			return sign(len(pa) - len(pb))
+		}
+		if c := strings.Compare(pa, pb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// splitPrefix splits the leading digits (or non-digits) from the rest of the string
+func splitPrefix(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// order returns the weight of the i-th character of the non-digit part
+func order(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	}
+	return int(c) + 256
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+// Bump returns the next version: major, minor and patch increment the
+// corresponding upstream component, resetting the lower ones and dropping any suffix,
+// and restart the revision; a pre-release (1.2~rc1) of the version the part
+// points at is released instead, so patch and minor turn 1.2~rc1-3 into 1.2-1
+// while major turns it into 2.0-1; revision increments the last number of the
+// revision (or of the upstream version of a native package)
+func (v *Version) Bump(part string) (*Version, error) {
+	next := *v
+	switch part {
+	case BumpMajor, BumpMinor, BumpPatch:
+		idx := map[string]int{BumpMajor: 0, BumpMinor: 1, BumpPatch: 2}[part]
+		components := numericComponents(v.Upstream)
+		if !isPreRelease(v.Upstream, components, idx) {
+			for len(components) <= idx {
+				components = append(components, 0)
+			}
+			components[idx]++
+		}
+		// the lower components are reset keeping the number of components,
+		// so 1.2.0 becomes 1.3.0 and 1.2 becomes 1.3
+		for i := idx + 1; i < len(components); i++ {
+			components[i] = 0
+		}
+		for len(components) < 2 {
+			components = append(components, 0)
+		}
+		parts := make([]string, 0, len(components))
+		for _, c := range components {
+			parts = append(parts, strconv.Itoa(c))
+		}
+		next.Upstream = strings.Join(parts, ".")
+		if next.Revision != "" {
+			next.Revision = "1"
+		}
+	case BumpRevision, "":
+		if next.Revision != "" {
+			next.Revision = incrementLastNumber(next.Revision)
+		} else {
+			next.Upstream = incrementLastNumber(next.Upstream)
+		}
+	default:
+		return nil, fmt.Errorf("unknown version part '%s', use one of %s, %s, %s or %s", part, BumpMajor, BumpMinor, BumpPatch, BumpRevision)
+	}
+	return &next, nil
+}
+
+// isPreRelease reports whether the upstream version is a pre-release of the
+// version having the components below idx reset, like 1.2~rc1 of 1.2 or 1.2.0
+func isPreRelease(upstream string, components []int, idx int) bool {
+	if !strings.Contains(upstream, "~") {
+		return false
+	}
+	for i := idx + 1; i < len(components); i++ {
+		if components[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// numericComponents returns the leading dot separated numbers of the version
+func numericComponents(s string) []int {
+	components := make([]int, 0, 3)
+	for _, part := range strings.Split(s, ".") {
+		digits, rest := splitPrefix(part, true)
+		if digits == "" {
+			break
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			break
+		}
+		components = append(components, n)
+		if rest != "" {
+			break
+		}
+	}
+	return components
+}
+
+// incrementLastNumber increments the last number in the string or appends 1 if there is none
+func incrementLastNumber(s string) string {
+	end := len(s)
+	for end > 0 && !isDigit(s[end-1]) {
+		end--
+	}
+	if end == 0 {
+		return s + "1"
+	}
+	start := end
+	for start > 0 && isDigit(s[start-1]) {
+		start--
+	}
+	n, err := strconv.Atoi(s[start:end])
+	if err != nil {
+		return s + "1"
+	}
+	return s[:start] + strconv.Itoa(n+1) + s[end:]
+}
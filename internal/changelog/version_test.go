@@ -0,0 +1,139 @@
+package changelog
+
+import (
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "1.0", b: "1.0", want: 0},
+		// the tilde sorts before anything, even the end of the version
+		{a: "1.0~rc1", b: "1.0", want: -1},
+		{a: "1.0~rc1", b: "1.0~rc2", want: -1},
+		{a: "1.0~~", b: "1.0~", want: -1},
+		{a: "1.0~rc1-1", b: "1.0-1", want: -1},
+		// the plus sorts after the end of the version
+		{a: "1.0+dfsg", b: "1.0", want: 1},
+		{a: "1.0+dfsg", b: "1.0.1", want: -1},
+		{a: "1.0~rc1", b: "1.0+dfsg", want: -1},
+		// the letters sort before the non-letters
+		{a: "1.0a", b: "1.0+", want: -1},
+		{a: "1.0a", b: "1.0b", want: -1},
+		// the epoch is compared first
+		{a: "1:1.0", b: "2.0", want: 1},
+		{a: "1:1.0", b: "2:0.1", want: -1},
+		{a: "0:1.0", b: "1.0", want: 0},
+		// the missing revision is equal to -0
+		{a: "1.0", b: "1.0-0", want: 0},
+		{a: "1.0", b: "1.0-1", want: -1},
+		// the digits are compared numerically, the rest lexically
+		{a: "1.10", b: "1.9", want: 1},
+		{a: "1.010", b: "1.10", want: 0},
+		{a: "1.0-10", b: "1.0-9", want: 1},
+		{a: "1.0-1ubuntu2", b: "1.0-1ubuntu10", want: -1},
+		{a: "1.0-1build1", b: "1.0-1ubuntu1", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			got, err := CompareVersions(tt.a, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			got, err = CompareVersions(tt.b, tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != -tt.want {
+				t.Errorf("CompareVersions(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    Version
+	}{
+		{version: "1.2.3", want: Version{Upstream: "1.2.3"}},
+		{version: "1.2.3-1", want: Version{Upstream: "1.2.3", Revision: "1"}},
+		{version: "2:1.2-3-4", want: Version{Epoch: 2, Upstream: "1.2-3", Revision: "4"}},
+		{version: "1.2~rc1+dfsg-0ubuntu1", want: Version{Upstream: "1.2~rc1+dfsg", Revision: "0ubuntu1"}},
+	}
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Errorf("%s: %v", tt.version, err)
+			continue
+		}
+		if *v != tt.want {
+			t.Errorf("%s parsed as %+v, want %+v", tt.version, *v, tt.want)
+		}
+		if v.String() != tt.version {
+			t.Errorf("%s formatted as %s", tt.version, v.String())
+		}
+	}
+
+	for _, version := range []string{"", "a1.0", "x:1.0", "-1:1.0", "1.0-", "1.0_1", "1.0-1_1"} {
+		if _, err := ParseVersion(version); err == nil {
+			t.Errorf("%q is accepted", version)
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		version string
+		part    string
+		want    string
+	}{
+		{version: "1.2.3-2", part: BumpMajor, want: "2.0.0-1"},
+		{version: "1.2.3-2", part: BumpMinor, want: "1.3.0-1"},
+		{version: "1.2.3-2", part: BumpPatch, want: "1.2.4-1"},
+		{version: "1.2.3-2", part: BumpRevision, want: "1.2.3-3"},
+		{version: "1.2.3-2", part: "", want: "1.2.3-3"},
+		{version: "1:1.2-2", part: BumpMinor, want: "1:1.3-1"},
+		{version: "1.2", part: BumpPatch, want: "1.2.1"},
+		{version: "1", part: BumpMinor, want: "1.1"},
+		{version: "1.2+dfsg-1", part: BumpMinor, want: "1.3-1"},
+		{version: "1.2.3", part: BumpRevision, want: "1.2.4"},
+		{version: "1.2-1ubuntu1", part: BumpRevision, want: "1.2-1ubuntu2"},
+		{version: "1.2-build", part: BumpRevision, want: "1.2-build1"},
+		// the pre-release is released
+		{version: "1.2~rc1-3", part: BumpPatch, want: "1.2-1"},
+		{version: "1.2~rc1-3", part: BumpMinor, want: "1.2-1"},
+		{version: "1.2~rc1-3", part: BumpMajor, want: "2.0-1"},
+		{version: "2.0~beta", part: BumpMajor, want: "2.0"},
+		{version: "1.2.1~rc1", part: BumpMinor, want: "1.3.0"},
+		{version: "1.2~rc1-3", part: BumpRevision, want: "1.2~rc1-4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+" "+tt.part, func(t *testing.T) {
+			v, err := ParseVersion(tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, err := v.Bump(tt.part)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next.String() != tt.want {
+				t.Errorf("bumped to %s, want %s", next.String(), tt.want)
+			}
+			if next.Compare(v) <= 0 {
+				t.Errorf("%s isn't greater than %s", next.String(), tt.version)
+			}
+		})
+	}
+
+	v, _ := ParseVersion("1.0")
+	if _, err := v.Bump("build"); err == nil {
+		t.Error("unknown part is accepted")
+	}
+}
@@ -0,0 +1,171 @@
+package changelog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const defaultFileMode = 0644
+
+// writeChangelog replaces the changelog file with the document, with dryRun
+// the unified diff of the change is printed instead
+func writeChangelog(fileName string, doc io.WriterTo, dryRun bool) error {
+	buf := &bytes.Buffer{}
+	_, err := doc.WriteTo(buf)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		return printDiff(os.Stdout, fileName, buf.Bytes())
+	}
+	return writeFile(fileName, buf.Bytes())
+}
+
+// writeFile atomically replaces the file: the data is written to a unique
+// temporary file in the same directory, synced and renamed over the file
+// keeping its permissions; a symlink is resolved to replace its target
+func writeFile(fileName string, data []byte) error {
+	if target, err := filepath.EvalSymlinks(fileName); err == nil {
+		fileName = target
+	}
+	mode := os.FileMode(defaultFileMode)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir, base := filepath.Split(fileName)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// the temporary file is removed unless it's renamed
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), fileName)
+	if err != nil {
+		return err
+	}
+	// the rename is durable once the directory is synced, not every
+	// platform supports that, so the error is ignored
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// printDiff writes the git style unified diff of the file and the new data
+func printDiff(w io.Writer, fileName string, data []byte) error {
+	old, err := os.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	mode := filemode.Regular
+	if info, err := os.Stat(fileName); err == nil && info.Mode().Perm()&0111 != 0 {
+		mode = filemode.Executable
+	}
+	path := filepath.ToSlash(fileName)
+	p := &filePatch{
+		to: &patchFile{path: path, mode: mode, hash: plumbing.ComputeHash(plumbing.BlobObject, data)},
+	}
+	if old != nil {
+		p.from = &patchFile{path: path, mode: mode, hash: plumbing.ComputeHash(plumbing.BlobObject, old)}
+	}
+	for _, d := range diff.Do(string(old), string(data)) {
+		op := fdiff.Equal
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			op = fdiff.Delete
+		}
+		p.chunks = append(p.chunks, &patchChunk{content: d.Text, op: op})
+	}
+	return fdiff.NewUnifiedEncoder(w, fdiff.DefaultContextLines).Encode(p)
+}
+
+// filePatch implements diff.Patch and diff.FilePatch for a single file
+type filePatch struct {
+	from, to *patchFile
+	chunks   []fdiff.Chunk
+}
+
+func (p *filePatch) FilePatches() []fdiff.FilePatch {
+	return []fdiff.FilePatch{p}
+}
+
+func (p *filePatch) Message() string {
+	return ""
+}
+
+func (p *filePatch) IsBinary() bool {
+	return false
+}
+
+func (p *filePatch) Files() (fdiff.File, fdiff.File) {
+	// a nil *patchFile must be returned as a nil interface
+	if p.from == nil {
+		return nil, p.to
+	}
+	return p.from, p.to
+}
+
+func (p *filePatch) Chunks() []fdiff.Chunk {
+	return p.chunks
+}
+
+type patchFile struct {
+	path string
+	mode filemode.FileMode
+	hash plumbing.Hash
+}
+
+func (f *patchFile) Hash() plumbing.Hash {
+	return f.hash
+}
+
+func (f *patchFile) Mode() filemode.FileMode {
+	return f.mode
+}
+
+func (f *patchFile) Path() string {
+	return f.path
+}
+
+type patchChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (c *patchChunk) Content() string {
+	return c.content
+}
+
+func (c *patchChunk) Type() fdiff.Operation {
+	return c.op
+}
@@ -1,18 +1,25 @@
 package cli
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/urfave/cli/v2"
 	"github.com/urfave/cli/v2/altsrc"
 	"github.com/xanzy/go-gitlab"
+	"gopkg.in/yaml.v3"
 
 	"github.com/lexycore/gitlab-tools/internal/changelog"
 	"github.com/lexycore/gitlab-tools/internal/client"
 	"github.com/lexycore/gitlab-tools/internal/config"
 	"github.com/lexycore/gitlab-tools/internal/operation"
+	"github.com/lexycore/gitlab-tools/internal/output"
+	"github.com/lexycore/gitlab-tools/internal/util"
 	"github.com/lexycore/gitlab-tools/version"
 )
 
@@ -20,10 +27,13 @@ const (
 	envPrefix = "GT_"
 
 	gitLabURLDefault         = "https://gitlab.com/"
-	gitLabTokenDefault       = "your-token-goes-here"
+	gitLabTokenDefault       = config.PlaceholderToken
 	gitLabGroupDefault       = ""
+	subgroupDepthDefault     = -1
+	outputDefault            = output.FormatTable
+	releaseTagPrefixDefault  = "v"
+	cloneConcurrencyDefault  = 4
 	cloneNonEmptyOnlyDefault = false
-	configFileNameDefault    = ".gitlab-tool.yml"
 )
 
 var (
@@ -36,6 +46,11 @@ type CLI struct {
 	Config   *config.Config
 	Git      *gitlab.Client
 	BasePath *config.GitLabPath
+	// Layers are the merged config files
+	Layers *config.Layers
+	// flagSources are the sources of the global flags set on the command
+	// line or by environment variables
+	flagSources map[string]string
 }
 
 // Run is the entry point to the CLI app
@@ -52,39 +67,68 @@ func CreateCLI() *CLI {
 	c.app.Usage = version.Usage
 	c.app.Version = version.Version()
 	c.app.Flags = []cli.Flag{
-		&cli.StringFlag{
+		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    "gitlab-url",
 			Aliases: []string{"u"},
 			Value:   gitLabURLDefault,
 			EnvVars: []string{envPrefix + "GITLAB_URL"},
 			Usage:   "Your GitLab server URL",
-		},
-		&cli.StringFlag{
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    "gitlab-token",
 			Aliases: []string{"t"},
 			Value:   gitLabTokenDefault,
 			EnvVars: []string{envPrefix + "GITLAB_TOKEN"},
 			Usage:   "Your GitLab access token",
-		},
-		&cli.StringFlag{
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "token-file",
+			EnvVars: []string{envPrefix + "TOKEN_FILE"},
+			Usage:   "File containing the GitLab access token, used if the token isn't set",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "token-command",
+			EnvVars: []string{envPrefix + "TOKEN_COMMAND"},
+			Usage:   "Command printing the GitLab access token, e.g. 'pass show gitlab', used if the token isn't set",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
 			Name:    "gitlab-group",
 			Aliases: []string{"g"},
 			Value:   gitLabGroupDefault,
 			EnvVars: []string{envPrefix + "GITLAB_GROUP"},
 			Usage:   "GitLab project group",
-		},
-		&cli.StringSliceFlag{
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
 			Name:    "exclude-projects",
 			Aliases: []string{"e"},
 			Value:   cli.NewStringSlice(excludeProjectsDefault...),
 			EnvVars: []string{envPrefix + "EXCLUDE_PROJECTS"},
 			Usage:   "GitLab projects to exclude",
-		},
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "subgroup-depth",
+			Value:   subgroupDepthDefault,
+			EnvVars: []string{envPrefix + "SUBGROUP_DEPTH"},
+			Usage:   "Levels of nested subgroups to walk through, -1 means no limit",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Value:   outputDefault,
+			EnvVars: []string{envPrefix + "OUTPUT"},
+			Usage:   "Output format: " + strings.Join(output.Formats, ", ") + " or a Go template",
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "profile",
+			Aliases: []string{"p"},
+			EnvVars: []string{envPrefix + "PROFILE"},
+			Usage:   "Server profile of the config file, selected by the URL host if not set",
+		}),
 		&cli.StringFlag{
 			Name:    "config-file",
 			Aliases: []string{"c"},
-			Value:   ".gitlab-tool.yml",
-			Usage:   "Application config file",
+			EnvVars: []string{envPrefix + "CONFIG_FILE"},
+			Usage:   "Application config file, if not set " + config.ConfigFileName + " files are looked for in the working directory and its parents up to the home directory, ~/.gitlab and /etc/gitlab",
 		},
 	}
 	c.app.Commands = cli.Commands{
@@ -97,30 +141,55 @@ func CreateCLI() *CLI {
 					Name:   "projects",
 					Usage:  "get projects from gitlab group",
 					Action: c.getProjects,
+					Flags:  []cli.Flag{outputFlag()},
 				},
 				{
 					Name:   "tags",
 					Usage:  "get projects latest tags",
 					Action: c.getTags,
+					Flags:  []cli.Flag{outputFlag()},
 				},
 				{
 					Name:    "merge-requests",
 					Aliases: []string{"mrs"},
-					Usage:   "get projects latest tags",
+					Usage:   "get merge requests merged since the latest release",
 					Action:  c.getMRs,
+					Flags:   []cli.Flag{outputFlag()},
+				},
+				{
+					Name:   "unreleased",
+					Usage:  "get merge requests merged into default branches after the latest tags",
+					Action: c.getUnreleased,
+					Flags:  []cli.Flag{outputFlag()},
 				},
 			},
 		},
 		{
-			Name:   "clone",
-			Usage:  "clone project or group of projects",
-			Action: c.clone,
+			Name:      "clone",
+			Usage:     "clone project or group of projects",
+			ArgsUsage: "<project or group URL> [directory]",
+			Action:    c.clone,
 			Flags: []cli.Flag{
-				&cli.StringFlag{
+				&cli.StringSliceFlag{
 					Name:    "exclude",
 					Aliases: []string{"e"},
 					Usage:   "projects to exclude",
 				},
+				&cli.BoolFlag{
+					Name:  "update",
+					Usage: "pull already cloned projects instead of skipping them",
+				},
+				&cli.IntFlag{
+					Name:    "concurrency",
+					Aliases: []string{"j"},
+					Value:   cloneConcurrencyDefault,
+					EnvVars: []string{envPrefix + "CLONE_CONCURRENCY"},
+					Usage:   "number of projects cloned at the same time",
+				},
+				&cli.BoolFlag{
+					Name:  "mirror",
+					Usage: "create bare mirror repositories (<group>/<project>.git) instead of worktrees",
+				},
 				// &cli.BoolFlag{
 				// 	Name:    "non-empty",
 				// 	Aliases: []string{"n"},
@@ -130,6 +199,30 @@ func CreateCLI() *CLI {
 				// },
 			},
 		},
+		{
+			Name:      "sync",
+			Usage:     "keep a local copy of a group of projects up to date",
+			ArgsUsage: "<group URL> <directory>",
+			Action:    c.sync,
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:    "exclude",
+					Aliases: []string{"e"},
+					Usage:   "projects to exclude",
+				},
+				&cli.IntFlag{
+					Name:    "concurrency",
+					Aliases: []string{"j"},
+					Value:   cloneConcurrencyDefault,
+					EnvVars: []string{envPrefix + "CLONE_CONCURRENCY"},
+					Usage:   "number of projects synced at the same time",
+				},
+				&cli.BoolFlag{
+					Name:  "move-archived",
+					Usage: "move projects archived, removed or renamed on the server to the _archived folder",
+				},
+			},
+		},
 		{
 			Name:    "changelog",
 			Aliases: []string{"chl"},
@@ -150,6 +243,12 @@ func CreateCLI() *CLI {
 							Aliases: []string{"v"},
 							Usage:   "version number",
 						},
+						&cli.StringFlag{
+							Name:    "bump",
+							Aliases: []string{"b"},
+							Value:   changelog.BumpRevision,
+							Usage:   "version part to increment if the version isn't set: major, minor, patch or revision",
+						},
 						&cli.StringFlag{
 							Name:    "release",
 							Aliases: []string{"r"},
@@ -175,16 +274,277 @@ func CreateCLI() *CLI {
 							Aliases: []string{"d"},
 							Usage:   "update date",
 						},
+						&cli.StringFlag{
+							Name:    "format",
+							Aliases: []string{"f"},
+							Usage:   "changelog format: debian, markdown or rpm, detected by the existing file if not set",
+						},
+						&cli.StringSliceFlag{
+							Name:  "added",
+							Usage: "added feature, the flag can be repeated",
+						},
+						&cli.StringSliceFlag{
+							Name:  "changed",
+							Usage: "changed behaviour, the flag can be repeated",
+						},
+						&cli.StringSliceFlag{
+							Name:  "fixed",
+							Usage: "fixed bug, the flag can be repeated",
+						},
+						&cli.BoolFlag{
+							Name:    "dry-run",
+							Aliases: []string{"n"},
+							Usage:   "print the diff of the changelog instead of writing it",
+						},
+					},
+				},
+				{
+					Name:   "release",
+					Usage:  "finalize the top UNRELEASED changelog section",
+					Action: c.releaseChangelog,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:    "distribution",
+							Aliases: []string{"D"},
+							Usage:   "target distribution, the latest released one by default",
+						},
+						&cli.StringFlag{
+							Name:    "maintainer",
+							Aliases: []string{"m"},
+							Usage:   "maintainer name and email",
+						},
+						&cli.StringFlag{
+							Name:    "date",
+							Aliases: []string{"d"},
+							Usage:   "release date",
+						},
+						&cli.BoolFlag{
+							Name:  "tag",
+							Usage: "commit the changelog and tag the commit, nothing is pushed",
+						},
+						&cli.StringFlag{
+							Name:  "tag-prefix",
+							Value: releaseTagPrefixDefault,
+							Usage: "prefix of the version tag",
+						},
+						&cli.BoolFlag{
+							Name:    "dry-run",
+							Aliases: []string{"n"},
+							Usage:   "print the diff of the changelog instead of writing it",
+						},
+					},
+				},
+				{
+					Name:      "show",
+					Usage:     "print the changelog section of the version, the latest one by default",
+					ArgsUsage: "[version]",
+					Action:    c.showChangelog,
+					Flags: []cli.Flag{
+						outputFlag(),
+						&cli.StringFlag{
+							Name:    "format",
+							Aliases: []string{"f"},
+							Usage:   "changelog format: debian, markdown or rpm, detected by the existing file if not set",
+						},
+					},
+				},
+				{
+					Name:   "list",
+					Usage:  "list the changelog versions with their dates and distributions",
+					Action: c.listChangelog,
+					Flags: []cli.Flag{
+						outputFlag(),
+						&cli.StringFlag{
+							Name:    "format",
+							Aliases: []string{"f"},
+							Usage:   "changelog format: debian, markdown or rpm, detected by the existing file if not set",
+						},
+					},
+				},
+				{
+					Name:      "lint",
+					Usage:     "check the Debian changelog, exits with non-zero code if errors are found",
+					ArgsUsage: "[file]",
+					Action:    c.lintChangelog,
+					Flags:     []cli.Flag{outputFlag()},
+				},
+				{
+					Name:   "convert",
+					Usage:  "convert the changelog history between debian, rpm and markdown formats",
+					Action: c.convertChangelog,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "from",
+							Usage: "source changelog format, detected by the existing file if not set",
+						},
+						&cli.StringFlag{
+							Name:     "to",
+							Usage:    "target changelog format",
+							Required: true,
+						},
+						&cli.BoolFlag{
+							Name:    "write",
+							Aliases: []string{"w"},
+							Usage:   "add the items missing in the target changelog file instead of printing the result",
+						},
+						&cli.BoolFlag{
+							Name:    "dry-run",
+							Aliases: []string{"n"},
+							Usage:   "print the diff of the changelog instead of writing it",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "configuration operations",
+			Subcommands: cli.Commands{
+				{
+					Name:   "where",
+					Usage:  "show the configuration values and the files (or flags) they come from",
+					Action: c.configWhere,
+					Flags:  []cli.Flag{outputFlag()},
+				},
+				{
+					Name:      "validate",
+					Usage:     "check the config files for unknown keys, wrong types and URLs, exits with non-zero code if problems are found",
+					ArgsUsage: "[file]",
+					Action:    c.configValidate,
+					Flags:     []cli.Flag{outputFlag()},
+				},
+				{
+					Name:   "init",
+					Usage:  "write the commented config file template, the global flags given on the command line are written too",
+					Action: c.configInit,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:    "file",
+							Aliases: []string{"f"},
+							Usage:   "config file to write, the --config-file or " + config.ConfigFileName + " in the working directory by default",
+						},
+						&cli.BoolFlag{
+							Name:  "force",
+							Usage: "overwrite the existing file",
+						},
+						&cli.BoolFlag{
+							Name:  "non-interactive",
+							Usage: "don't ask for the settings not given on the command line",
+						},
+					},
+				},
+				{
+					Name:      "get",
+					Usage:     "print the value of the key, the keys are the global flag names, profiles, branch-model and tls",
+					ArgsUsage: "<key>",
+					Action:    c.configGet,
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "show-secrets",
+							Usage: "print the tokens as is instead of masking them",
+						},
+					},
+				},
+				{
+					Name:      "set",
+					Usage:     "set the value of the key in the config file preserving its comments",
+					ArgsUsage: "<key> <value>",
+					Action:    c.configSet,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:    "file",
+							Aliases: []string{"f"},
+							Usage:   "config file to edit, the --config-file or the closest config file by default",
+						},
 					},
 				},
 			},
 		},
 	}
-	c.app.Before = altsrc.InitInputSourceWithContext(c.app.Flags, altsrc.NewYamlSourceFromFlagFunc("config-file"))
+	loadFlags := altsrc.InitInputSourceWithContext(c.app.Flags, c.loadConfig)
+	c.app.Before = func(ctx *cli.Context) error {
+		err := loadFlags(ctx)
+		if err != nil {
+			return err
+		}
+		return c.applyEmptyValues(ctx)
+	}
 	c.app.Action = c.main
 	return c
 }
 
+// loadConfig merges the config files into the source of the flags values
+func (c *CLI) loadConfig(ctx *cli.Context) (altsrc.InputSourceContext, error) {
+	// the flags are checked before the config values are applied to them
+	c.flagSources = make(map[string]string)
+	for _, name := range ctx.LocalFlagNames() {
+		c.flagSources[name] = "command line"
+	}
+	for _, flag := range c.app.Flags {
+		name := flag.Names()[0]
+		if _, ok := c.flagSources[name]; !ok && ctx.IsSet(name) {
+			c.flagSources[name] = "$" + envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		}
+	}
+
+	var err error
+	c.Layers, err = config.Discover(ctx.String("config-file"))
+	if err != nil {
+		// the commands not needing the config work with a broken one
+		fmt.Fprintf(os.Stderr, "warning: %s\n", strings.TrimPrefix(err.Error(), "error: "))
+		c.Layers, _ = config.LoadLayers(nil)
+	}
+	// the unknown keys are ignored by the flags, so the typos are reported
+	// unless config validate reports them anyway
+	problems, err := c.Layers.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if args := ctx.Args().Slice(); len(args) < 2 || args[0] != "config" || args[1] != "validate" {
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", problem)
+		}
+	}
+	files := make([]string, 0, len(c.Layers.Files))
+	for _, file := range c.Layers.Files {
+		files = append(files, file.Path)
+	}
+	return altsrc.NewMapInputSource(strings.Join(files, ", "), c.Layers.Map(problems.InvalidKeys()...)), nil
+}
+
+// applyEmptyValues sets the flags to the empty strings and the non-positive
+// numbers of the config files, altsrc skips them as unset, so an empty
+// token-command or subgroup-depth 0 of a closer file would lose to a farther one
+func (c *CLI) applyEmptyValues(ctx *cli.Context) error {
+	for _, flag := range c.app.Flags {
+		name := flag.Names()[0]
+		if _, ok := c.flagSources[name]; ok {
+			continue
+		}
+		value := ""
+		switch v := c.Layers.Values[name].(type) {
+		case string:
+			if _, ok := flag.(*altsrc.StringFlag); !ok || v != "" {
+				continue
+			}
+		case int:
+			if _, ok := flag.(*altsrc.IntFlag); !ok || v > 0 {
+				continue
+			}
+			value = strconv.Itoa(v)
+		default:
+			continue
+		}
+		for _, alias := range flag.Names() {
+			err := ctx.Set(alias, value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (c *CLI) main(ctx *cli.Context) error {
 	// Config := &Config{
 	// 	GitLabURL:   ctx.String("gitlab-url"),
@@ -212,38 +572,138 @@ func determineGitLabPath(path string) (*config.GitLabPath, error) {
 }
 
 func (c *CLI) initClient(ctx *cli.Context, checkArg bool) (*config.GitLabPath, error) {
+	path, err := c.initConfig(ctx, checkArg)
+	if err != nil {
+		return nil, err
+	}
+	// the missing token is reported before any API call
+	err = c.Config.ResolveToken()
+	if err != nil {
+		return path, err
+	}
+	c.Git, err = client.InitClient(c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return path, nil
+}
+
+// initConfig sets up the config of the client from the flags, the config files
+// and the profile, the token is left for the client to resolve
+func (c *CLI) initConfig(ctx *cli.Context, checkArg bool) (*config.GitLabPath, error) {
 	var path *config.GitLabPath
 	var err error
 	c.Config = &config.Config{
 		GitLabURL:       ctx.String("gitlab-url"),
 		GitLabToken:     ctx.String("gitlab-token"),
+		TokenFile:       ctx.String("token-file"),
+		TokenCommand:    ctx.String("token-command"),
 		GitLabGroup:     ctx.String("gitlab-group"),
 		ExcludeProjects: ctx.StringSlice("exclude-projects"),
+		SubgroupDepth:   ctx.Int("subgroup-depth"),
 	}
+	c.Config.BranchModels, err = c.Layers.BranchModels()
+	if err != nil {
+		return nil, err
+	}
+	c.Config.Profiles, err = c.Layers.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	c.Config.TLS, err = c.Layers.TLS()
+	if err != nil {
+		return nil, err
+	}
+	// the settings given explicitly aren't overridden by the profile
+	skip := make(map[string]bool)
+	for name := range c.flagSources {
+		skip[name] = true
+	}
+	serverURL := c.Config.GitLabURL
 	if checkArg {
 		path, err = determineGitLabPath(ctx.Args().First())
 		if err == nil {
 			c.Config.GitLabURL = path.Server
 			c.Config.GitLabGroup = path.Group
+			serverURL = path.Server
+			skip["gitlab-url"] = true
+			skip["gitlab-group"] = true
 		}
 	}
-	// Make sure the given URL ends with a slash
-	if !strings.HasSuffix(c.Config.GitLabURL, "/") {
-		c.Config.GitLabURL += "/"
+	var profile *config.Profile
+	c.Config.Profile, profile, err = config.SelectProfile(c.Config.Profiles, ctx.String("profile"), serverURL)
+	if err != nil {
+		return nil, err
 	}
-	c.Git, err = client.InitClient(c.Config)
+	switch {
+	case profile != nil:
+		profile.Apply(c.Config, skip)
+	case path != nil && !config.SameHost(ctx.String("gitlab-url"), path.Server) && !c.tokenOnCommandLine():
+		// the token of the configured server isn't sent to the server of the URL
+		c.Config = c.Config.ForServer(path.Server)
+	}
+	err = c.Config.TLS.LoadCA()
 	if err != nil {
 		return nil, err
 	}
+	// Make sure the given URL ends with a slash
+	if !strings.HasSuffix(c.Config.GitLabURL, "/") {
+		c.Config.GitLabURL += "/"
+	}
 	return path, nil
 }
 
+// tokenOnCommandLine reports whether the token is given for this very run
+func (c *CLI) tokenOnCommandLine() bool {
+	for _, name := range []string{"gitlab-token", "token-file", "token-command"} {
+		if c.flagSources[name] == "command line" {
+			return true
+		}
+	}
+	return false
+}
+
+// outputFlag lets the commands take --output after the command name too
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "output",
+		Aliases: []string{"o"},
+		Usage:   "Output format: " + strings.Join(output.Formats, ", ") + " or a Go template, overrides the global --output",
+	}
+}
+
+// outputFormat returns the --output of the command, or the global one if the
+// command doesn't set it
+func outputFormat(ctx *cli.Context) string {
+	if ctx.IsSet("output") {
+		return ctx.String("output")
+	}
+	return appContext(ctx).String("output")
+}
+
+// appContext returns the context of the application holding the global flags,
+// the commands may have the flags of the same names
+func appContext(ctx *cli.Context) *cli.Context {
+	lineage := ctx.Lineage()
+	// the outermost context has no application
+	for i := len(lineage) - 1; i >= 0; i-- {
+		if lineage[i].App != nil {
+			return lineage[i]
+		}
+	}
+	return ctx
+}
+
 func (c *CLI) getProjects(ctx *cli.Context) error {
 	_, err := c.initClient(ctx, false)
 	if err != nil {
 		return err
 	}
-	return operation.GetProjectRepos(c.Git, c.Config)
+	result, err := operation.GetProjectRepos(c.Git, c.Config)
+	if err != nil {
+		return err
+	}
+	return output.Render(os.Stdout, outputFormat(ctx), result)
 }
 
 func (c *CLI) getTags(ctx *cli.Context) error {
@@ -251,7 +711,11 @@ func (c *CLI) getTags(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	return operation.GetProjectReposTags(c.Git, c.Config)
+	result, err := operation.GetProjectReposTags(c.Git, c.Config)
+	if err != nil {
+		return err
+	}
+	return output.Render(os.Stdout, outputFormat(ctx), result)
 }
 
 func (c *CLI) getMRs(ctx *cli.Context) error {
@@ -259,7 +723,23 @@ func (c *CLI) getMRs(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	return operation.GetProjectReposMRs(c.Git, c.Config)
+	result, err := operation.GetProjectReposMRs(c.Git, c.Config)
+	if err != nil {
+		return err
+	}
+	return output.Render(os.Stdout, outputFormat(ctx), result)
+}
+
+func (c *CLI) getUnreleased(ctx *cli.Context) error {
+	_, err := c.initClient(ctx, false)
+	if err != nil {
+		return err
+	}
+	result, err := operation.GetUnreleased(c.Git, c.Config)
+	if err != nil {
+		return err
+	}
+	return output.Render(os.Stdout, outputFormat(ctx), result)
 }
 
 func (c *CLI) clone(ctx *cli.Context) error {
@@ -267,15 +747,53 @@ func (c *CLI) clone(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	exclude := ctx.String("exclude")
-	return operation.Clone(c.Git, c.Config, path, exclude)
+	if path == nil {
+		return operation.ErrRepoNotFound
+	}
+	opt := &operation.CloneOptions{
+		Destination: ctx.Args().Get(1),
+		Exclude:     ctx.StringSlice("exclude"),
+		Update:      ctx.Bool("update"),
+		Concurrency: ctx.Int("concurrency"),
+		Mirror:      ctx.Bool("mirror"),
+	}
+	if opt.Destination == "" {
+		opt.Destination = "."
+	}
+	return operation.Clone(c.Git, c.Config, path, opt)
+}
+
+func (c *CLI) sync(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		// the help alone would exit with zero code
+		_ = cli.ShowCommandHelp(ctx, "sync")
+		return fmt.Errorf("error: sync requires <group URL> <directory>")
+	}
+	path, err := c.initClient(ctx, true)
+	if err != nil {
+		return err
+	}
+	if path == nil {
+		return operation.ErrRepoNotFound
+	}
+	opt := &operation.SyncOptions{
+		CloneOptions: operation.CloneOptions{
+			Destination: ctx.Args().Get(1),
+			Exclude:     ctx.StringSlice("exclude"),
+			Concurrency: ctx.Int("concurrency"),
+		},
+		MoveArchived: ctx.Bool("move-archived"),
+	}
+	return operation.Sync(c.Git, c.Config, path, opt)
 }
 
 func (c *CLI) addChangelog(ctx *cli.Context) error {
-	// path, err := c.initClient(ctx, true)
-	// if err != nil {
-	// 	return err
-	// }
+	// the project is determined by the git remote of the current directory,
+	// the client is created only if the changes are taken from the merge requests
+	_, err := c.initConfig(ctx, false)
+	if err != nil {
+		return err
+	}
 	path := &config.GitLabPath{}
 	newItem := changelog.Item{
 		Package:    ctx.String("package"),
@@ -286,5 +804,306 @@ func (c *CLI) addChangelog(ctx *cli.Context) error {
 		Maintainer: ctx.String("maintainer"),
 		Date:       ctx.String("date"),
 	}
-	return changelog.Add(c.Git, c.Config, path, &newItem)
+	opt := &changelog.AddOptions{
+		Bump:   ctx.String("bump"),
+		Format: ctx.String("format"),
+		DryRun: ctx.Bool("dry-run"),
+	}
+	for _, category := range []string{changelog.CategoryAdded, changelog.CategoryChanged, changelog.CategoryFixed} {
+		for _, text := range ctx.StringSlice(strings.ToLower(category)) {
+			opt.Changes = append(opt.Changes, changelog.Change{Category: category, Text: text})
+		}
+	}
+	return changelog.Add(c.Git, c.Config, path, &newItem, opt)
+}
+
+func (c *CLI) releaseChangelog(ctx *cli.Context) error {
+	opt := &changelog.ReleaseOptions{
+		Distribution: ctx.String("distribution"),
+		Maintainer:   ctx.String("maintainer"),
+		Date:         ctx.String("date"),
+		Tag:          ctx.Bool("tag"),
+		TagPrefix:    ctx.String("tag-prefix"),
+		DryRun:       ctx.Bool("dry-run"),
+	}
+	return changelog.Release(opt)
+}
+
+func (c *CLI) convertChangelog(ctx *cli.Context) error {
+	opt := &changelog.ConvertOptions{
+		From:   ctx.String("from"),
+		To:     ctx.String("to"),
+		Write:  ctx.Bool("write"),
+		DryRun: ctx.Bool("dry-run"),
+	}
+	return changelog.Convert(os.Stdout, opt)
+}
+
+func (c *CLI) lintChangelog(ctx *cli.Context) error {
+	findings, err := changelog.Lint(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	err = output.Render(os.Stdout, outputFormat(ctx), findings)
+	if err != nil {
+		return err
+	}
+	if n := findings.Errors(); n > 0 {
+		return fmt.Errorf("changelog lint: %d errors found", n)
+	}
+	return nil
+}
+
+func (c *CLI) showChangelog(ctx *cli.Context) error {
+	entry, err := changelog.Show(ctx.String("format"), ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	// the section is printed as it's written unless another output is requested
+	if outputFormat(ctx) == output.FormatTable {
+		fmt.Print(entry.Text)
+		return nil
+	}
+	return output.Render(os.Stdout, outputFormat(ctx), entry)
+}
+
+func (c *CLI) listChangelog(ctx *cli.Context) error {
+	entries, err := changelog.List(ctx.String("format"))
+	if err != nil {
+		return err
+	}
+	return output.Render(os.Stdout, outputFormat(ctx), entries)
+}
+
+func (c *CLI) configWhere(ctx *cli.Context) error {
+	sources := c.Layers.Sources()
+	global := appContext(ctx)
+	// the flags set on the command line or by environment variables
+	// override the config files
+	for _, flag := range c.app.Flags {
+		name := flag.Names()[0]
+		origin, ok := c.flagSources[name]
+		if !ok {
+			continue
+		}
+		var value interface{}
+		switch flag.(type) {
+		case *altsrc.StringSliceFlag:
+			value = global.StringSlice(name)
+		case *altsrc.IntFlag:
+			value = global.Int(name)
+		default:
+			value = global.String(name)
+		}
+		source := &config.Source{Key: name}
+		for _, s := range sources {
+			if s.Key == name {
+				source = s
+			}
+		}
+		if source.Source == "" {
+			sources = append(sources, source)
+		}
+		source.Value = config.MaskSecret(name, value)
+		source.Source = origin
+	}
+	return output.Render(os.Stdout, outputFormat(ctx), sources)
+}
+
+// configFlag returns the global flag the config key is bound to or nil
+func (c *CLI) configFlag(key string) cli.Flag {
+	for _, flag := range c.app.Flags {
+		if name := flag.Names()[0]; name == key && name != "config-file" {
+			return flag
+		}
+	}
+	return nil
+}
+
+// configValue returns the value of the flag as YAML
+func configValue(flag cli.Flag, value string) (string, error) {
+	switch flag.(type) {
+	case *altsrc.StringSliceFlag:
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, strconv.Quote(item))
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case *altsrc.IntFlag:
+		if _, err := strconv.Atoi(value); err != nil {
+			return "", fmt.Errorf("error: %s should be a number", flag.Names()[0])
+		}
+		return value, nil
+	}
+	return strconv.Quote(value), nil
+}
+
+func (c *CLI) configInit(ctx *cli.Context) error {
+	path := ctx.String("file")
+	if path == "" {
+		path = ctx.String("config-file")
+	}
+	if path == "" {
+		path = config.ConfigFileName
+	}
+	values := make(map[string]string)
+	for _, flag := range c.app.Flags {
+		name := flag.Names()[0]
+		if c.flagSources[name] != "command line" || c.configFlag(name) == nil {
+			continue
+		}
+		err := checkTrusted(ctx, path, name)
+		if err != nil {
+			return err
+		}
+		value := ctx.String(name)
+		if _, ok := flag.(*altsrc.StringSliceFlag); ok {
+			value = strings.Join(ctx.StringSlice(name), ",")
+		}
+		yamlValue, err := configValue(flag, value)
+		if err != nil {
+			return err
+		}
+		values[name] = yamlValue
+	}
+	if !ctx.Bool("non-interactive") && isTerminal(os.Stdin) {
+		reader := bufio.NewReader(os.Stdin)
+		for _, name := range []string{"gitlab-url", "gitlab-group", "token-command"} {
+			if _, ok := values[name]; ok || checkTrusted(ctx, path, name) != nil {
+				continue
+			}
+			flag := c.configFlag(name)
+			fmt.Printf("%s (%s) [%s]: ", name, flag.(cli.DocGenerationFlag).GetUsage(), ctx.String(name))
+			answer, err := reader.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			if answer = strings.TrimSpace(answer); answer != "" {
+				values[name], _ = configValue(flag, answer)
+			}
+		}
+	}
+	err := config.InitFile(path, values, ctx.Bool("force"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s\n", path)
+	return nil
+}
+
+// checkTrusted refuses to write token-file and token-command to a project
+// config file, they would be ignored there
+func checkTrusted(ctx *cli.Context, path string, key string) error {
+	if !config.UntrustedKey(key) || config.TrustedFile(path) || path == ctx.String("config-file") {
+		return nil
+	}
+	return fmt.Errorf("error: %s would be ignored in %s, set it in ~/%s, ~/.gitlab or /etc/gitlab", key, path, config.ConfigFileName)
+}
+
+// isTerminal reports whether the file is a character device other than the null device
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(info, null)
+}
+
+func (c *CLI) configValidate(ctx *cli.Context) error {
+	var problems config.Problems
+	var err error
+	if file := ctx.Args().First(); file != "" {
+		problems, err = config.ValidateFile(file)
+	} else {
+		problems, err = c.Layers.Validate()
+	}
+	if err != nil {
+		return err
+	}
+	err = output.Render(os.Stdout, outputFormat(ctx), problems)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("config validate: %d problems found", len(problems))
+	}
+	return nil
+}
+
+func (c *CLI) configGet(ctx *cli.Context) error {
+	key := ctx.Args().First()
+	if key == "" {
+		return fmt.Errorf("error: config key is required")
+	}
+	value, ok := c.Layers.Lookup(key)
+	if !ok {
+		flag := c.configFlag(key)
+		if flag == nil {
+			return fmt.Errorf("error: %s is not set", key)
+		}
+		// the flag default or the value from the environment
+		switch flag.(type) {
+		case *altsrc.StringSliceFlag:
+			value = ctx.StringSlice(key)
+		case *altsrc.IntFlag:
+			value = ctx.Int(key)
+		default:
+			value = ctx.String(key)
+		}
+	}
+	if !ctx.Bool("show-secrets") {
+		value = config.MaskSecret(key, value)
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}, []string:
+		b, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(b))
+	default:
+		fmt.Println(value)
+	}
+	return nil
+}
+
+func (c *CLI) configSet(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("error: config key and value are required")
+	}
+	key, value := ctx.Args().Get(0), ctx.Args().Get(1)
+	section := strings.SplitN(key, ".", 2)[0]
+	if flag := c.configFlag(key); flag != nil {
+		var err error
+		value, err = configValue(flag, value)
+		if err != nil {
+			return err
+		}
+	} else if !util.ContainsString(&config.Sections, section) || section == key {
+		return fmt.Errorf("error: unknown config key '%s'", key)
+	}
+
+	path := ctx.String("file")
+	if path == "" {
+		path = ctx.String("config-file")
+	}
+	if path == "" && len(c.Layers.Files) > 0 {
+		path = c.Layers.Files[0].Path
+	}
+	if path == "" {
+		path = config.ConfigFileName
+	}
+	err := checkTrusted(ctx, path, key)
+	if err != nil {
+		return err
+	}
+	err = config.SetValue(path, key, value)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("set %s in %s\n", key, path)
+	return nil
 }
@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/xanzy/go-gitlab"
 
@@ -9,7 +10,19 @@ import (
 )
 
 func InitClient(cfg *config.Config) (*gitlab.Client, error) {
-	git, err := gitlab.NewClient(cfg.GitLabToken, gitlab.WithBaseURL(fmt.Sprintf("%sapi/v4/", cfg.GitLabURL)))
+	options := []gitlab.ClientOptionFunc{
+		gitlab.WithBaseURL(fmt.Sprintf("%sapi/v4/", cfg.GitLabURL)),
+	}
+	if cfg.TLS.IsSet() {
+		tlsConfig, err := cfg.TLS.ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		options = append(options, gitlab.WithHTTPClient(&http.Client{Transport: transport}))
+	}
+	git, err := gitlab.NewClient(cfg.GitLabToken, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,49 @@
+package config
+
+// BranchModel describes the branches merge requests go through on the way to a release
+type BranchModel struct {
+	// Mainline is the branch features are merged into,
+	// the project default branch is used if it's empty
+	Mainline string `yaml:"mainline"`
+	// Release is the branch (or a pattern like release/*) the mainline is merged
+	// into to make a release, releases are determined by tags only if it's empty
+	Release string `yaml:"release"`
+}
+
+// BranchModels contains the global branch model and the project specific ones
+type BranchModels struct {
+	BranchModel `yaml:",inline"`
+	// Projects overrides the global model, keyed by the project path with namespace
+	Projects map[string]BranchModel `yaml:"projects"`
+}
+
+// For returns the branch model of the project, the project settings take
+// precedence over the global ones and the default branch fills the gaps
+func (m *BranchModels) For(project string, defaultBranch string) BranchModel {
+	if m == nil {
+		return BranchModel{Mainline: defaultBranch}
+	}
+	model := m.BranchModel
+	if projectModel, ok := m.Projects[project]; ok {
+		if projectModel.Mainline != "" {
+			model.Mainline = projectModel.Mainline
+		}
+		if projectModel.Release != "" {
+			model.Release = projectModel.Release
+		}
+	}
+	if model.Mainline == "" {
+		model.Mainline = defaultBranch
+	}
+	return model
+}
+
+// BranchModels returns the branch-model section of the config files
+func (l *Layers) BranchModels() (*BranchModels, error) {
+	models := &BranchModels{}
+	err := l.Decode("branch-model", models)
+	if err != nil {
+		return nil, err
+	}
+	return models, nil
+}
@@ -1,10 +1,6 @@
 package config
 
 import (
-	"fmt"
-	"os"
-	"strings"
-
 	"github.com/xanzy/go-gitlab"
 )
 
@@ -20,41 +16,39 @@ type GitLabPath struct {
 	Project string
 }
 
+// FullPath returns the path of the project (or group) with its namespace
+func (p *GitLabPath) FullPath() string {
+	if p.Group == "" {
+		return p.Project
+	}
+	return p.Group + "/" + p.Project
+}
+
 // Config represents common gitlab-tools settings
 type Config struct {
-	GitLabURL       string
-	GitLabToken     string
+	GitLabURL   string
+	GitLabToken string
+	// TokenFile and TokenCommand provide the token if it isn't set
+	TokenFile       string
+	TokenCommand    string
 	GitLabGroup     string
 	ExcludeProjects []string
+	// SubgroupDepth limits the levels of nested subgroups to walk through,
+	// 0 means the group itself only, a negative value means no limit
+	SubgroupDepth int
+	BranchModels  *BranchModels
+	// Profile is the name of the selected profile, empty if there is none
+	Profile  string
+	Profiles map[string]*Profile
+	TLS      TLS
 }
 
-const configFileName = ".gitlab-tool.yml"
+// ConfigFileName is the name of the config files looked for by FindGitlabToolConfig
+const ConfigFileName = ".gitlab-tool.yml"
 
+// cfgPaths are the directories searched for the config file after the
+// working directory and its parents
 var cfgPaths = []string{
 	"~/.gitlab",
 	"/etc/gitlab",
 }
-
-func FindGitlabToolConfig(configFileName string) (string, error) {
-	var cfgPathsCalc []string
-
-	homeCfg := fmt.Sprintf("%s/%s", strings.TrimRight(os.ExpandEnv("~"), "/"), configFileName)
-	curPath, err := os.Getwd()
-	if err == nil {
-		curPathItems := strings.Split(strings.TrimRight(curPath, "/"), "/")
-		cfgPathsCalc = make([]string, 0, len(curPathItems)+len(cfgPaths))
-		for i := len(curPathItems); i >= 0; i-- {
-			iPath := strings.Join(append(curPathItems[0:i], configFileName), "/")
-			cfgPathsCalc = append(cfgPathsCalc, iPath)
-			if iPath == homeCfg {
-				break
-			}
-		}
-	}
-	for _, cfgPath := range append(cfgPathsCalc, cfgPaths...) {
-		if _, err := os.Stat(cfgPath); err != nil {
-			return "", err
-		}
-	}
-	return "", nil
-}
@@ -0,0 +1,345 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexycore/gitlab-tools/internal/util"
+)
+
+// Layer is a config file contributing to the configuration
+type Layer struct {
+	Path   string
+	Values map[string]interface{}
+	// Trusted is false for the project files, their token-file and
+	// token-command are ignored
+	Trusted bool
+}
+
+// untrustedKeys are the keys reading files or running commands, they are
+// accepted from the trusted config files only
+var untrustedKeys = []string{"token-file", "token-command"}
+
+// Layers is the configuration merged from the config files
+type Layers struct {
+	// Files are the config files, the closest (overriding the others) first
+	Files []*Layer
+	// Values are the merged values
+	Values map[string]interface{}
+	// Origins maps the dotted keys of the values to the files they come from
+	Origins map[string]string
+	// Problems are the config files which can't be read, they are left out
+	Problems Problems
+}
+
+// FindGitlabToolConfig returns the existing config files, the closest first:
+// the files in the working directory and its parents up to the home
+// directory (or the root outside of the home directory), then the files in
+// ~/.gitlab and /etc/gitlab
+func FindGitlabToolConfig(configFileName string) ([]string, error) {
+	candidates := make([]string, 0)
+	home, _ := os.UserHomeDir()
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		candidates = append(candidates, filepath.Join(dir, configFileName))
+		parent := filepath.Dir(dir)
+		if dir == home || parent == dir {
+			break
+		}
+		dir = parent
+	}
+	for _, cfgPath := range cfgPaths {
+		candidates = append(candidates, filepath.Join(expandHome(cfgPath, home), configFileName))
+	}
+
+	found := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		found = append(found, candidate)
+	}
+	return found, nil
+}
+
+// expandHome replaces the leading ~ with the home directory
+func expandHome(path string, home string) string {
+	if home == "" || path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// TrustedFile reports whether the config file is in the home directory,
+// ~/.gitlab or /etc/gitlab, unlike the files of the projects which come
+// with the checkouts
+func TrustedFile(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	home, _ := os.UserHomeDir()
+	dirs := make([]string, 0, len(cfgPaths)+1)
+	if home != "" {
+		dirs = append(dirs, home)
+	}
+	for _, cfgPath := range cfgPaths {
+		dirs = append(dirs, expandHome(cfgPath, home))
+	}
+	for _, dir := range dirs {
+		if filepath.Dir(abs) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadLayers reads and merges the config files given the closest first,
+// the values of the closer files override the farther ones, the mappings
+// are merged key by key; the files which can't be read are left out and
+// recorded in Problems, the token-file and token-command of the files
+// other than the TrustedFile ones are left out
+func LoadLayers(paths []string) (*Layers, error) {
+	return loadLayers(paths, TrustedFile)
+}
+
+func loadLayers(paths []string, trusted func(path string) bool) (*Layers, error) {
+	layers := &Layers{
+		Files:    make([]*Layer, 0, len(paths)),
+		Values:   make(map[string]interface{}),
+		Origins:  make(map[string]string),
+		Problems: make(Problems, 0),
+	}
+	for _, path := range paths {
+		values := make(map[string]interface{})
+		err := readCommandYaml(path, &values)
+		if err != nil {
+			// a broken file in a parent directory shouldn't break the commands
+			layers.Problems = append(layers.Problems, fileProblem(path, err))
+			continue
+		}
+		layer := &Layer{Path: path, Values: values, Trusted: trusted(path)}
+		if !layer.Trusted {
+			dropUntrusted(values)
+		}
+		layers.Files = append(layers.Files, layer)
+	}
+	// the farthest file is applied first
+	for i := len(layers.Files) - 1; i >= 0; i-- {
+		merge(layers.Values, layers.Files[i].Values, layers.Origins, "", layers.Files[i].Path)
+	}
+	return layers, nil
+}
+
+// merge copies the src values into dst recording the origin of every leaf value
+func merge(dst, src map[string]interface{}, origins map[string]string, prefix string, origin string) {
+	for key, value := range src {
+		dotted := prefix + key
+		if srcMap, ok := toStringMap(value); ok {
+			dstMap, ok := toStringMap(dst[key])
+			if !ok {
+				dstMap = make(map[string]interface{})
+				dropOrigins(origins, dotted)
+			}
+			merge(dstMap, srcMap, origins, dotted+".", origin)
+			dst[key] = dstMap
+			continue
+		}
+		dropOrigins(origins, dotted)
+		dst[key] = value
+		origins[dotted] = origin
+	}
+}
+
+// UntrustedKey reports whether the dotted key is accepted from the trusted
+// config files only
+func UntrustedKey(key string) bool {
+	name := key[strings.LastIndex(key, ".")+1:]
+	return util.ContainsString(&untrustedKeys, name)
+}
+
+// dropUntrusted removes the untrustedKeys from the values and the nested mappings
+func dropUntrusted(values map[string]interface{}) {
+	for key, value := range values {
+		if util.ContainsString(&untrustedKeys, key) {
+			delete(values, key)
+			continue
+		}
+		if m, ok := toStringMap(value); ok {
+			dropUntrusted(m)
+			values[key] = m
+		}
+	}
+}
+
+// dropOrigins forgets the origins of the key and its nested keys replaced by another value
+func dropOrigins(origins map[string]string, key string) {
+	for k := range origins {
+		if k == key || strings.HasPrefix(k, key+".") {
+			delete(origins, k)
+		}
+	}
+}
+
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			converted[fmt.Sprint(k)] = v
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+// Map returns the merged values in the form altsrc.NewMapInputSource expects,
+// the skipped keys are left out
+func (l *Layers) Map(skip ...string) map[interface{}]interface{} {
+	values := make(map[interface{}]interface{}, len(l.Values))
+	for key, value := range l.Values {
+		if !util.ContainsString(&skip, key) {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// Decode decodes the merged value of the key into out, out isn't changed
+// if there is no such key
+func (l *Layers) Decode(key string, out interface{}) error {
+	value, ok := l.Values[key]
+	if !ok {
+		return nil
+	}
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, out)
+}
+
+// Keys returns the sorted dotted keys of the merged values
+func (l *Layers) Keys() []string {
+	keys := make([]string, 0, len(l.Origins))
+	for key := range l.Origins {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lookup returns the merged value of the dotted key
+func (l *Layers) Lookup(key string) (interface{}, bool) {
+	var node interface{} = l.Values
+	for _, section := range strings.Split(key, ".") {
+		m, ok := toStringMap(node)
+		if !ok {
+			return nil, false
+		}
+		node, ok = m[section]
+		if !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// Discover loads the config file set explicitly, which is trusted wherever
+// it is, or merges the config files found by FindGitlabToolConfig
+func Discover(configFile string) (*Layers, error) {
+	if configFile != "" {
+		return loadLayers([]string{configFile}, func(string) bool { return true })
+	}
+	paths, err := FindGitlabToolConfig(ConfigFileName)
+	if err != nil {
+		return nil, err
+	}
+	return LoadLayers(paths)
+}
+
+// Source is a configuration value along with the file (or the flag or the
+// environment variable) it comes from
+type Source struct {
+	Key    string      `json:"key" yaml:"key"`
+	Value  interface{} `json:"value" yaml:"value"`
+	Source string      `json:"source" yaml:"source"`
+}
+
+// Sources is the result of config where
+type Sources []*Source
+
+func (s Sources) Header() []string {
+	return []string{"key", "value", "source"}
+}
+
+func (s Sources) Rows() [][]string {
+	rows := make([][]string, 0, len(s))
+	for _, source := range s {
+		rows = append(rows, []string{source.Key, formatValue(source.Value), source.Source})
+	}
+	return rows
+}
+
+func formatValue(value interface{}) string {
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, ", ")
+	}
+	if values, ok := value.([]interface{}); ok {
+		items := make([]string, 0, len(values))
+		for _, v := range values {
+			items = append(items, fmt.Sprint(v))
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// Sources returns the merged values with the files they come from,
+// the secrets are masked
+func (l *Layers) Sources() Sources {
+	sources := make(Sources, 0, len(l.Origins))
+	for _, key := range l.Keys() {
+		value, _ := l.Lookup(key)
+		sources = append(sources, &Source{
+			Key:    key,
+			Value:  MaskSecret(key, value),
+			Source: l.Origins[key],
+		})
+	}
+	return sources
+}
+
+// MaskSecret hides all but the last 4 characters of the token values,
+// the keys like token-file are not secrets, the mappings are masked key by key
+func MaskSecret(key string, value interface{}) interface{} {
+	if m, ok := toStringMap(value); ok {
+		masked := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			masked[k] = MaskSecret(key+"."+k, v)
+		}
+		return masked
+	}
+	s, ok := value.(string)
+	key = strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	if !ok || key != "token" && !strings.HasSuffix(key, "-token") {
+		return value
+	}
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return strings.Repeat("*", len(s)-4) + s[len(s)-4:]
+}
@@ -0,0 +1,288 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sections are the config file keys besides the flag names
+var Sections = []string{"profiles", "branch-model", "tls"}
+
+// Template is the config file written by config init, the defaults are
+// commented out, so a project file doesn't override the farther files;
+// the keys are the names of the global flags
+const Template = `# gitlab-tool configuration
+#
+# The files named .gitlab-tool.yml are merged from the working directory up to
+# the home directory, then ~/.gitlab and /etc/gitlab, the closer files override
+# the farther ones; 'gitlab-tool config where' shows where the values come from.
+# The command line flags and GT_* environment variables override the files.
+
+# GitLab server URL
+# gitlab-url: https://gitlab.com/
+
+# GitLab group the commands work with by default
+# gitlab-group: ""
+
+# The token is better read from a file or printed by a command than kept here
+# gitlab-token: ""
+
+# File containing the GitLab access token, the token file and the command
+# are taken from the files in the home directory, ~/.gitlab and /etc/gitlab only
+# token-file: ""
+
+# Command printing the GitLab access token, e.g. 'pass show gitlab'
+# token-command: ""
+
+# Projects to skip
+# exclude-projects: []
+
+# Levels of nested subgroups to walk through, -1 means no limit
+# subgroup-depth: -1
+
+# Output format: table, json, yaml, csv or a Go template
+# output: table
+
+# Server profile, selected by the host of the URL if not set
+# profile: ""
+
+# Server profiles selected by --profile or by the host of the URL
+# profiles:
+#   work:
+#     url: https://gitlab.example.com/
+#     token-command: pass show gitlab-work
+#     group: team
+#     exclude-projects: [sandbox]
+#     tls:
+#       ca-file: /etc/ssl/certs/work-ca.pem
+
+# Branches merge requests go through on the way to a release
+# branch-model:
+#   mainline: develop
+#   release: master
+#   projects:
+#     group/project:
+#       release: release/*
+
+# TLS settings of the server
+# tls:
+#   ca-file: /etc/ssl/certs/ca.pem
+#   insecure-skip-verify: false
+`
+
+// InitFile writes the config template, the values set replace their
+// commented defaults, an existing file is overwritten only with force
+func InitFile(path string, values map[string]string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("error: %s already exists, use --force to overwrite it", path)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	text := Template
+	for _, key := range keys {
+		var err error
+		text, err = setText(text, key, values[key])
+		if err != nil {
+			return err
+		}
+	}
+	return writeFile(path, []byte(text))
+}
+
+// setText sets the key in the YAML text keeping it as it is otherwise, unlike
+// setNode it keeps the comments of a file with no keys: the commented out
+// key is replaced, or the key is appended
+func setText(text string, key string, value string) (string, error) {
+	doc, err := parseDocument(nil)
+	if err == nil {
+		err = setNode(doc, key, value)
+	}
+	if err != nil {
+		return "", err
+	}
+	b, err := encodeDocument(doc)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if idx := commentedKey(lines, key); idx >= 0 {
+		lines[idx] = string(b)
+		return strings.Join(lines, ""), nil
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if text != "" {
+		text += "\n"
+	}
+	return text + string(b), nil
+}
+
+// commentedKey returns the index of the line with the commented out key or -1
+func commentedKey(lines []string, key string) int {
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# "+key+":") {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetValue sets the dotted key of the config file to the value parsed as
+// YAML, the comments and the order of the keys are preserved
+func SetValue(path string, key string, value string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	doc, err := parseDocument(b)
+	if err != nil {
+		return fmt.Errorf("error: config file %s: %s", path, err.Error())
+	}
+	// the comments of the file with no keys, like the one written by
+	// InitFile, would be lost by setNode
+	root := doc.Content[0]
+	commented := commentedKey(strings.SplitAfter(string(b), "\n"), key) >= 0
+	empty := len(root.Content) == 0 && root.Style&yaml.FlowStyle == 0
+	if empty || commented && mappingIndex(root, key) < 0 {
+		text, err := setText(string(b), key, value)
+		if err != nil {
+			return err
+		}
+		return writeFile(path, []byte(text))
+	}
+	err = setNode(doc, key, value)
+	if err != nil {
+		return err
+	}
+	return writeDocument(path, doc)
+}
+
+// parseDocument parses the YAML document, the empty document is a mapping
+func parseDocument(b []byte) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	err := yaml.Unmarshal(b, doc)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the document should be a mapping")
+	}
+	return doc, nil
+}
+
+// setNode sets the dotted key, the missing mappings on the way are created
+func setNode(doc *yaml.Node, key string, value string) error {
+	valueDoc := &yaml.Node{}
+	err := yaml.Unmarshal([]byte(value), valueDoc)
+	if err != nil {
+		return fmt.Errorf("error: value of %s: %s", key, err.Error())
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ""}
+	if len(valueDoc.Content) > 0 {
+		valueNode = valueDoc.Content[0]
+	}
+	plainScalars(valueNode)
+
+	node := doc.Content[0]
+	sections := strings.Split(key, ".")
+	for i, section := range sections {
+		idx := mappingIndex(node, section)
+		last := i == len(sections)-1
+		if idx < 0 {
+			child := valueNode
+			if !last {
+				child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			// an empty flow mapping like {} gets the block style with its first key
+			node.Style &^= yaml.FlowStyle
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: section}, child)
+			node = child
+			continue
+		}
+		current := node.Content[idx+1]
+		if last {
+			// the comments of the replaced value are kept
+			valueNode.HeadComment = current.HeadComment
+			valueNode.LineComment = current.LineComment
+			valueNode.FootComment = current.FootComment
+			node.Content[idx+1] = valueNode
+			return nil
+		}
+		if current.Kind != yaml.MappingNode {
+			return fmt.Errorf("error: %s isn't a mapping", strings.Join(sections[:i+1], "."))
+		}
+		node = current
+	}
+	return nil
+}
+
+// plainScalars drops the quotes of the scalars, the encoder quotes back
+// only the ones which would be read as another type
+func plainScalars(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode {
+		node.Style &^= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+	}
+	for _, child := range node.Content {
+		plainScalars(child)
+	}
+}
+
+// mappingIndex returns the index of the key node in the mapping or -1
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeDocument writes the document to the file
+func writeDocument(path string, doc *yaml.Node) error {
+	b, err := encodeDocument(doc)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, b)
+}
+
+func encodeDocument(doc *yaml.Node) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	err := enc.Encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFile writes the file keeping its permissions, the new files are
+// readable by the owner only since they may contain tokens
+func writeFile(path string, b []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	return ioutil.WriteFile(path, b, mode)
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// TLS contains the TLS settings of a GitLab server
+type TLS struct {
+	InsecureSkipVerify bool `yaml:"insecure-skip-verify"`
+	// CAFile is the PEM bundle of the certificates trusted besides the system ones
+	CAFile string `yaml:"ca-file"`
+	// CABundle is the content of CAFile loaded by LoadCA
+	CABundle []byte `yaml:"-"`
+}
+
+// Profile contains the settings of a GitLab server
+type Profile struct {
+	URL             string   `yaml:"url" schema:"url"`
+	Token           string   `yaml:"token"`
+	TokenFile       string   `yaml:"token-file"`
+	TokenCommand    string   `yaml:"token-command"`
+	Group           string   `yaml:"group"`
+	ExcludeProjects []string `yaml:"exclude-projects"`
+	TLS             TLS      `yaml:"tls"`
+}
+
+// Profiles returns the profiles section of the config files keyed by the profile names
+func (l *Layers) Profiles() (map[string]*Profile, error) {
+	profiles := make(map[string]*Profile)
+	err := l.Decode("profiles", &profiles)
+	if err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// TLS returns the tls section of the config files used when no profile sets it
+func (l *Layers) TLS() (TLS, error) {
+	settings := TLS{}
+	err := l.Decode("tls", &settings)
+	return settings, err
+}
+
+// SelectProfile returns the profile by its name, or the profile of the server
+// URL host if the name is empty; no profile is selected if none matches
+func SelectProfile(profiles map[string]*Profile, name string, serverURL string) (string, *Profile, error) {
+	if name != "" {
+		profile, ok := profiles[name]
+		if !ok {
+			return "", nil, fmt.Errorf("error: unknown profile '%s', available profiles: %s", name, strings.Join(profileNames(profiles), ", "))
+		}
+		return name, profile, nil
+	}
+	host := urlHost(serverURL)
+	if host == "" {
+		return "", nil, nil
+	}
+	// the names are sorted to select the same profile if several match
+	for _, name := range profileNames(profiles) {
+		if urlHost(profiles[name].URL) == host {
+			return name, profiles[name], nil
+		}
+	}
+	return "", nil, nil
+}
+
+func profileNames(profiles map[string]*Profile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SameHost reports whether both URLs point at the same host and port
+func SameHost(a, b string) bool {
+	host := urlHost(a)
+	return host != "" && host == urlHost(b)
+}
+
+// urlHost returns the lower case host with the port of the URL
+func urlHost(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// IsSet reports whether any TLS setting differs from the defaults
+func (t *TLS) IsSet() bool {
+	return t.InsecureSkipVerify || t.CAFile != ""
+}
+
+// LoadCA reads the CA file into CABundle
+func (t *TLS) LoadCA() error {
+	if t.CAFile == "" || t.CABundle != nil {
+		return nil
+	}
+	bundle, err := ioutil.ReadFile(t.CAFile)
+	if err != nil {
+		return err
+	}
+	t.CABundle = bundle
+	return nil
+}
+
+// ClientConfig returns the TLS config of the GitLab API client
+func (t *TLS) ClientConfig() (*tls.Config, error) {
+	err := t.LoadCA()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CABundle != nil {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(t.CABundle) {
+			return nil, fmt.Errorf("error: no certificates found in %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// Apply overrides the config with the non-empty profile settings, skip lists
+// the settings (by their flag names) which shouldn't be overridden
+func (p *Profile) Apply(cfg *Config, skip map[string]bool) {
+	if p.URL != "" && !skip["gitlab-url"] {
+		cfg.GitLabURL = p.URL
+	}
+	// the token providers of the profile replace the token given less explicitly
+	tokenSet := skip["gitlab-token"] || skip["token-file"] || skip["token-command"]
+	if !tokenSet && (p.Token != "" || p.TokenFile != "" || p.TokenCommand != "") {
+		cfg.GitLabToken = p.Token
+		cfg.TokenFile = p.TokenFile
+		cfg.TokenCommand = p.TokenCommand
+	}
+	if p.Group != "" && !skip["gitlab-group"] {
+		cfg.GitLabGroup = p.Group
+	}
+	if len(p.ExcludeProjects) > 0 && !skip["exclude-projects"] {
+		cfg.ExcludeProjects = p.ExcludeProjects
+	}
+	if p.TLS.IsSet() {
+		cfg.TLS = p.TLS
+	}
+}
+
+// ForServer returns the config to access another GitLab server, the token
+// and the TLS settings of the matching profile are used if there is one,
+// otherwise the token is looked for in ~/.netrc and git credentials, so the
+// token of one server is never sent to another one
+func (c *Config) ForServer(serverURL string) *Config {
+	server := *c
+	server.GitLabURL = serverURL
+	server.GitLabToken = ""
+	server.TokenFile = ""
+	server.TokenCommand = ""
+	if _, profile, _ := SelectProfile(c.Profiles, "", serverURL); profile != nil {
+		skip := map[string]bool{"gitlab-url": true, "gitlab-group": true, "exclude-projects": true}
+		profile.Apply(&server, skip)
+	}
+	// the anonymous access is used without the token
+	_ = server.ResolveToken()
+	return &server
+}
@@ -0,0 +1,331 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lexycore/gitlab-tools/internal/util"
+)
+
+// File is the schema of the config files: the global flags and the sections
+type File struct {
+	GitLabURL       string              `yaml:"gitlab-url" schema:"url"`
+	GitLabToken     string              `yaml:"gitlab-token"`
+	TokenFile       string              `yaml:"token-file"`
+	TokenCommand    string              `yaml:"token-command"`
+	GitLabGroup     string              `yaml:"gitlab-group"`
+	ExcludeProjects []string            `yaml:"exclude-projects"`
+	SubgroupDepth   int                 `yaml:"subgroup-depth"`
+	Output          string              `yaml:"output"`
+	Profile         string              `yaml:"profile"`
+	Profiles        map[string]*Profile `yaml:"profiles"`
+	BranchModel     *BranchModels       `yaml:"branch-model"`
+	TLS             TLS                 `yaml:"tls"`
+}
+
+// Validation checks
+const (
+	CheckRead   = "unreadable"
+	CheckSyntax = "syntax"
+	CheckKey    = "unknown-key"
+	CheckType   = "invalid-type"
+	CheckURL    = "invalid-url"
+	CheckTrust  = "untrusted-key"
+)
+
+// reYamlError matches the syntax errors and the first of the unmarshal errors
+var reYamlError = regexp.MustCompile(`^yaml: (?:unmarshal errors:\n\s*)?line (\d+): (.*)`)
+
+// Problem is a mistake in a config file found by Validate
+type Problem struct {
+	File    string `json:"file" yaml:"file"`
+	Line    int    `json:"line" yaml:"line"`
+	Column  int    `json:"column" yaml:"column"`
+	Key     string `json:"key" yaml:"key"`
+	Check   string `json:"check" yaml:"check"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (p *Problem) String() string {
+	switch {
+	case p.Line == 0:
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+// Problems is the result of Validate
+type Problems []*Problem
+
+func (p Problems) Header() []string {
+	return []string{"file", "line", "column", "key", "check", "message"}
+}
+
+func (p Problems) Rows() [][]string {
+	rows := make([][]string, 0, len(p))
+	for _, problem := range p {
+		rows = append(rows, []string{problem.File, strconv.Itoa(problem.Line), strconv.Itoa(problem.Column), problem.Key, problem.Check, problem.Message})
+	}
+	return rows
+}
+
+// InvalidKeys returns the top level keys with the values of wrong types
+func (p Problems) InvalidKeys() []string {
+	keys := make([]string, 0)
+	for _, problem := range p {
+		if problem.Check == CheckType && !strings.Contains(problem.Key, ".") {
+			keys = append(keys, problem.Key)
+		}
+	}
+	return keys
+}
+
+// Validate checks the config files against the File schema, the files
+// which couldn't be loaded are reported too
+func (l *Layers) Validate() (Problems, error) {
+	problems := make(Problems, 0, len(l.Problems))
+	problems = append(problems, l.Problems...)
+	for _, file := range l.Files {
+		found, err := validateFile(file.Path, file.Trusted)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// ValidateFile checks the unknown keys, the value types and the URLs of the
+// config file, the problems have the positions of the offending nodes;
+// token-file and token-command are reported unless it's a TrustedFile
+func ValidateFile(path string) (Problems, error) {
+	return validateFile(path, TrustedFile(path))
+}
+
+func validateFile(path string, trusted bool) (Problems, error) {
+	b, err := loadDataFrom(path)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{path: path, trusted: trusted, problems: make(Problems, 0)}
+	doc := &yaml.Node{}
+	err = yaml.Unmarshal(b, doc)
+	if err != nil {
+		return Problems{fileProblem(path, err)}, nil
+	}
+	if len(doc.Content) > 0 {
+		v.validate(doc.Content[0], reflect.TypeOf(File{}), "", "")
+	}
+	return v.problems, nil
+}
+
+// fileProblem describes the error of reading the file, the line of the YAML
+// error is extracted from its message
+func fileProblem(path string, err error) *Problem {
+	problem := &Problem{File: path, Check: CheckRead, Message: err.Error()}
+	if strings.HasPrefix(err.Error(), "yaml: ") {
+		problem.Check = CheckSyntax
+	}
+	if match := reYamlError.FindStringSubmatch(err.Error()); match != nil {
+		problem.Line, _ = strconv.Atoi(match[1])
+		problem.Message = match[2]
+	}
+	return problem
+}
+
+// validator collects the problems of a single file
+type validator struct {
+	path     string
+	trusted  bool
+	problems Problems
+}
+
+func (v *validator) report(node *yaml.Node, key string, check string, format string, args ...interface{}) {
+	v.problems = append(v.problems, &Problem{
+		File:    v.path,
+		Line:    node.Line,
+		Column:  node.Column,
+		Key:     key,
+		Check:   check,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validate checks the node against the type, the tag is the schema tag of the field
+func (v *validator) validate(node *yaml.Node, t reflect.Type, key string, tag string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.report(node, key, CheckType, "%s should be a mapping", describe(key))
+			return
+		}
+		fields := schemaFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			field, ok := fields[name]
+			if !ok {
+				v.unknownKey(node.Content[i], join(key, name), fields)
+				continue
+			}
+			if !v.trusted && util.ContainsString(&untrustedKeys, name) {
+				v.report(node.Content[i], join(key, name), CheckTrust, "%s is ignored in the project config files, set it in ~/%s, ~/.gitlab or /etc/gitlab", name, ConfigFileName)
+				continue
+			}
+			v.validate(node.Content[i+1], field.Type, join(key, name), field.Tag.Get("schema"))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.report(node, key, CheckType, "%s should be a mapping", describe(key))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.validate(node.Content[i+1], t.Elem(), join(key, node.Content[i].Value), "")
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.report(node, key, CheckType, "%s should be a list", describe(key))
+			return
+		}
+		for _, item := range node.Content {
+			v.validate(item, t.Elem(), key, tag)
+		}
+	case reflect.String:
+		// the flags accept the strings only, 123 or yes should be quoted
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+			v.report(node, key, CheckType, "%s should be a string", describe(key))
+			return
+		}
+		if tag == "url" {
+			v.validateURL(node, key)
+		}
+	case reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			v.report(node, key, CheckType, "%s should be a number", describe(key))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.report(node, key, CheckType, "%s should be true or false", describe(key))
+		}
+	}
+}
+
+func (v *validator) validateURL(node *yaml.Node, key string) {
+	if node.Value == "" {
+		return
+	}
+	u, err := url.Parse(node.Value)
+	if err != nil {
+		v.report(node, key, CheckURL, "%s is not a valid URL: %s", describe(key), err.Error())
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		v.report(node, key, CheckURL, "%s should be an http(s) URL like https://gitlab.com/", describe(key))
+	}
+}
+
+func (v *validator) unknownKey(node *yaml.Node, key string, fields map[string]reflect.StructField) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, distance := "", 3
+	for _, name := range names {
+		if d := levenshtein(node.Value, name); d < distance {
+			best, distance = name, d
+		}
+	}
+	if best != "" {
+		v.report(node, key, CheckKey, "unknown key '%s', did you mean '%s'?", node.Value, best)
+		return
+	}
+	v.report(node, key, CheckKey, "unknown key '%s', expected one of %s", node.Value, strings.Join(names, ", "))
+}
+
+// schemaFields maps the yaml names to the fields of the struct, the inline
+// structs are flattened and the skipped fields are left out
+func schemaFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		parts := strings.Split(field.Tag.Get("yaml"), ",")
+		name := parts[0]
+		if name == "-" {
+			continue
+		}
+		if len(parts) > 1 && parts[1] == "inline" {
+			for inlineName, inlineField := range schemaFields(field.Type) {
+				fields[inlineName] = inlineField
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func describe(key string) string {
+	if key == "" {
+		return "the config file"
+	}
+	return key
+}
+
+// levenshtein returns the number of the single character edits turning a into b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PlaceholderToken is the default value of the token flag, it means the token isn't set
+const PlaceholderToken = "your-token-goes-here"
+
+// ErrNoToken is returned by ResolveToken if no token provider has the token
+var ErrNoToken = errors.New("error: GitLab token is not set, use --gitlab-token, GT_GITLAB_TOKEN, " +
+	"gitlab-token, token-file or token-command in the config file, ~/.netrc or a git credential helper")
+
+// ResolveToken makes sure the config has the token: unless it's set
+// explicitly the token is read from the token file, the output of the token
+// command, ~/.netrc or git credential helpers, in this order
+func (c *Config) ResolveToken() error {
+	if c.GitLabToken != "" && c.GitLabToken != PlaceholderToken {
+		return nil
+	}
+	c.GitLabToken = ""
+	if c.TokenFile != "" {
+		token, err := tokenFromFile(c.TokenFile)
+		if err != nil {
+			return fmt.Errorf("error: token file: %s", err.Error())
+		}
+		c.GitLabToken = token
+		return nil
+	}
+	if c.TokenCommand != "" {
+		token, err := tokenFromCommand(c.TokenCommand)
+		if err != nil {
+			return fmt.Errorf("error: token command '%s': %s", c.TokenCommand, err.Error())
+		}
+		c.GitLabToken = token
+		return nil
+	}
+	u, err := url.Parse(c.GitLabURL)
+	if err != nil || u.Host == "" {
+		return ErrNoToken
+	}
+	// ~/.netrc and git credentials are optional, so their errors are ignored
+	if token, _ := tokenFromNetrc(u); token != "" {
+		c.GitLabToken = token
+		return nil
+	}
+	if token, _ := tokenFromGitCredential(u); token != "" {
+		c.GitLabToken = token
+		return nil
+	}
+	return ErrNoToken
+}
+
+// tokenFromFile returns the first line of the file
+func tokenFromFile(path string) (string, error) {
+	home, _ := os.UserHomeDir()
+	b, err := ioutil.ReadFile(expandHome(path, home))
+	if err != nil {
+		return "", err
+	}
+	token := firstLine(b)
+	if token == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return token, nil
+}
+
+// tokenFromCommand runs the command with the shell and returns the first
+// line of its output, like `pass show gitlab` prints the password
+func tokenFromCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	token := firstLine(out)
+	if token == "" {
+		return "", errors.New("empty output")
+	}
+	return token, nil
+}
+
+// tokenFromNetrc returns the password of the server host in $NETRC or ~/.netrc
+func tokenFromNetrc(u *url.URL) (string, error) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return netrcPassword(b, u.Host, u.Hostname()), nil
+}
+
+// netrcPassword returns the password of the first machine matching one of
+// the hosts, or of the default entry
+func netrcPassword(b []byte, hosts ...string) string {
+	fields := strings.Fields(string(b))
+	machine, password, defaultPassword := "", "", ""
+	inDefault := false
+	found := func() bool {
+		for _, host := range hosts {
+			if strings.EqualFold(machine, host) && password != "" {
+				return true
+			}
+		}
+		return false
+	}
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if found() {
+				return password
+			}
+			machine, password, inDefault = "", "", false
+			if i+1 < len(fields) {
+				i++
+				machine = fields[i]
+			}
+		case "default":
+			if found() {
+				return password
+			}
+			machine, password, inDefault = "", "", true
+		case "password":
+			if i+1 < len(fields) {
+				i++
+				password = fields[i]
+				if inDefault && defaultPassword == "" {
+					defaultPassword = password
+				}
+			}
+		case "login", "account":
+			i++
+		case "macdef":
+			// macros last up to an empty line which isn't kept by strings.Fields,
+			// so the rest of the file is skipped
+			i = len(fields)
+		}
+	}
+	if found() {
+		return password
+	}
+	return defaultPassword
+}
+
+// tokenFromGitCredential asks git credential helpers for the password of the
+// server, the user is never prompted
+func tokenFromGitCredential(u *url.URL) (string, error) {
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=%s\nhost=%s\n\n", u.Scheme, u.Host))
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if password := strings.TrimPrefix(scanner.Text(), "password="); password != scanner.Text() {
+			return password, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+func firstLine(b []byte) string {
+	return strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0])
+}
@@ -9,40 +9,9 @@ import (
 	"runtime"
 	"strings"
 
-	"github.com/urfave/cli/v2"
-	"github.com/urfave/cli/v2/altsrc"
 	"gopkg.in/yaml.v3"
 )
 
-type yamlSourceContext struct {
-	FilePath string
-}
-
-// NewYamlSourceFromFile creates a new Yaml InputSourceContext from a filepath.
-func NewYamlSourceFromFile(file string) (altsrc.InputSourceContext, error) {
-	ysc := &yamlSourceContext{FilePath: file}
-	var results map[interface{}]interface{}
-	err := readCommandYaml(ysc.FilePath, &results)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to load Yaml file '%s': inner error: \n'%v'", ysc.FilePath, err.Error())
-	}
-
-	return altsrc.NewMapInputSource(file, results), nil
-}
-
-// NewYamlSourceFromFlagFunc creates a new Yaml InputSourceContext from a provided flag name and source context.
-func NewYamlSourceFromFlagFunc(flagFileName string) func(context *cli.Context) (altsrc.InputSourceContext, error) {
-	return func(context *cli.Context) (altsrc.InputSourceContext, error) {
-		if context.IsSet(flagFileName) {
-			filePath := context.String(flagFileName)
-			return NewYamlSourceFromFile(filePath)
-		}
-
-		// altsrc.defaultInputSource()
-		return altsrc.NewMapInputSource("", map[interface{}]interface{}{}), nil
-	}
-}
-
 func readCommandYaml(filePath string, container interface{}) (err error) {
 	b, err := loadDataFrom(filePath)
 	if err != nil {
@@ -12,22 +12,17 @@ import (
 
 // take a look:
 // "github.com/gabrie30/ghorg"
-// "github.com/korovkin/limiter"
 
 var (
 	v = struct {
 		updated string
 		desc    string
 		created string
-		beta    string
-		master  string
 		merged  string
 	}{
 		updated: "updated",
 		desc:    "desc",
 		created: "created_at",
-		beta:    "beta",
-		master:  "master",
 		merged:  "merged",
 	}
 )
@@ -44,6 +39,7 @@ func NewClient(ctx *cli.Context) error {
 			GitLabToken:     ctx.String("gitlab-token"),
 			GitLabGroup:     ctx.String("gitlab-group"),
 			ExcludeProjects: ctx.StringSlice("exclude-projects"),
+			SubgroupDepth:   ctx.Int("subgroup-depth"),
 		},
 	}
 	// Make sure the given URL end with a slash
@@ -2,14 +2,36 @@ package operation
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/xanzy/go-gitlab"
 
 	"github.com/lexycore/gitlab-tools/internal/config"
 	"github.com/lexycore/gitlab-tools/internal/util"
 )
 
-func Clone(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, exclude string) error {
+// CloneOptions represents the options of the clone operation
+type CloneOptions struct {
+	// Destination is the base directory, repositories are cloned into
+	// Destination/<project path with namespace>
+	Destination string
+	// Exclude contains additional project names (or paths with namespace) to skip
+	Exclude []string
+	// Update pulls the existing checkouts instead of skipping them
+	Update bool
+	// Concurrency is the number of projects processed at the same time
+	Concurrency int
+	// Mirror creates bare mirror repositories instead of worktrees
+	Mirror bool
+}
+
+func Clone(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, opt *CloneOptions) error {
 	if path.Server == "" {
 		return ErrServerNotFound
 	}
@@ -18,102 +40,118 @@ func Clone(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, excl
 	}
 
 	if path.Project != "" {
-		return cloneRepo(git, cfg, path, exclude)
+		project, response, err := git.Projects.GetProject(path.FullPath(), nil)
+		if err == nil {
+			result := cloneRepo(cfg, project, opt)
+			fmt.Println(result)
+			return result.Err
+		}
+		if response == nil || response.StatusCode != http.StatusNotFound {
+			return err
+		}
+		// the path doesn't point to a project, so it should be a group
 	}
-	return cloneGroup(git, cfg, path, exclude)
+	return cloneGroup(git, cfg, path, opt)
 }
 
-func cloneGroup(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, exclude string) error {
-	group, _, err := git.Groups.GetGroup(cfg.GitLabGroup, nil)
+func cloneGroup(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, opt *CloneOptions) error {
+	projects, err := groupProjects(git, cfg, path.FullPath())
 	if err != nil {
 		return err
 	}
-	tagOpt := &gitlab.ListTagsOptions{
-		OrderBy: &v.updated,
-		Sort:    &v.desc,
-		ListOptions: gitlab.ListOptions{
-			Page:    1,
-			PerPage: 1,
-		},
-	}
-	i := 0
-	for _, repo := range group.Projects {
-		if util.ContainsString(&cfg.ExcludeProjects, repo.Name) {
+	included := make([]*gitlab.Project, 0, len(projects))
+	for _, repo := range projects {
+		if isExcluded(cfg, opt, repo) {
 			continue
 		}
-		i++
-		fmt.Println(i, ":", repo.Name)
-		tags, _, err := git.Tags.ListTags(repo.PathWithNamespace, tagOpt)
-		if err != nil {
-			return err
-		} // 64b24f9f8744cc4fe47638cb534cf656a164d59c
-		var mrRelease *gitlab.MergeRequest
-		for _, tag := range tags {
-			fmt.Println("\t-", tag.Commit.CreatedAt, ":", tag.Name, ":", tag.Commit.ID, ":", tag.Release)
-			mrOpt := &gitlab.ListProjectMergeRequestsOptions{
-				ListOptions: gitlab.ListOptions{
-					Page:    1,
-					PerPage: 5,
-				},
-				State:        &v.merged,
-				OrderBy:      &v.created,
-				Sort:         &v.desc,
-				TargetBranch: &v.beta,
-				Search:       nil,
-			}
-			readMRs := true
-			for readMRs {
-				mrs, response, err := git.MergeRequests.ListProjectMergeRequests(repo.PathWithNamespace, mrOpt)
-				if err != nil {
-					return err
-				}
-				mrOpt.ListOptions.Page = response.NextPage
-				for _, mr := range mrs {
-					if mr.SourceBranch == v.master {
-						mrRelease = mr
-						fmt.Println("\t\t- beta :", mr.CreatedAt, ":", mr.ID, ":", mr.SHA, ":", mr.MergeCommitSHA, ":", mr.Title)
-						readMRs = false
-						break
-					}
-				}
-			}
-			mrOpt = &gitlab.ListProjectMergeRequestsOptions{
-				ListOptions: gitlab.ListOptions{
-					Page:    1,
-					PerPage: 20,
-				},
-				State:        &v.merged,
-				OrderBy:      &v.created,
-				Sort:         &v.desc,
-				TargetBranch: &v.master,
-				Search:       nil,
-			}
-			readMRs = true
-			for mrOpt.ListOptions.Page > 0 {
-				mrsM, response, err := git.MergeRequests.ListProjectMergeRequests(repo.PathWithNamespace, mrOpt)
-				if err != nil {
-					return err
-				}
-				mrOpt.ListOptions.Page = response.NextPage
-				for _, mr := range mrsM {
-					if mrRelease != nil {
-						if mrRelease.SHA == mr.MergeCommitSHA {
-							fmt.Println("\t\t--------------------------------------")
-							mrOpt.ListOptions.Page = 0
-							readMRs = false
-						}
-					}
-					fmt.Println("\t\t- master :", mr.CreatedAt, ":", mr.ID, ":", mr.SHA, ":", mr.MergeCommitSHA, ":", mr.Title)
-					if !readMRs {
-						break
-					}
-				}
-			}
+		included = append(included, repo)
+	}
+	results := runPool(opt.Concurrency, included, func(project *gitlab.Project) *CloneResult {
+		return cloneRepo(cfg, project, opt)
+	})
+	return printSummary(results)
+}
+
+func cloneRepo(cfg *config.Config, project *gitlab.Project, opt *CloneOptions) *CloneResult {
+	if opt.Mirror {
+		return mirrorRepo(cfg, project, opt)
+	}
+	dir := projectDir(opt.Destination, project)
+	result := &CloneResult{
+		Project: project.PathWithNamespace,
+		Dir:     dir,
+	}
+	if project.EmptyRepo {
+		return result.skipped("empty repository")
+	}
+	if _, err := os.Stat(dir); err == nil {
+		if !opt.Update {
+			return result.skipped("already exists")
 		}
+		return updateRepo(cfg, project, result)
 	}
-	return nil
+	_, err := gogit.PlainClone(dir, false, &gogit.CloneOptions{
+		URL:             project.HTTPURLToRepo,
+		Auth:            repoAuth(cfg, project.HTTPURLToRepo),
+		InsecureSkipTLS: cfg.TLS.InsecureSkipVerify,
+		CABundle:        cfg.TLS.CABundle,
+	})
+	if err != nil {
+		return result.failed(err)
+	}
+	result.Status = StatusCloned
+	return result
 }
 
-func cloneRepo(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, exclude string) error {
-	return nil
+func updateRepo(cfg *config.Config, project *gitlab.Project, result *CloneResult) *CloneResult {
+	repo, err := gogit.PlainOpen(result.Dir)
+	if err != nil {
+		return result.failed(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return result.failed(err)
+	}
+	err = worktree.Pull(&gogit.PullOptions{
+		RemoteName:      gogit.DefaultRemoteName,
+		Auth:            repoAuth(cfg, project.HTTPURLToRepo),
+		InsecureSkipTLS: cfg.TLS.InsecureSkipVerify,
+		CABundle:        cfg.TLS.CABundle,
+	})
+	if err == gogit.NoErrAlreadyUpToDate {
+		return result.skipped("already up to date")
+	}
+	if err != nil {
+		return result.failed(err)
+	}
+	result.Status = StatusUpdated
+	return result
+}
+
+// isExcluded checks the project against both the global and the clone exclude lists
+func isExcluded(cfg *config.Config, opt *CloneOptions, project *gitlab.Project) bool {
+	for _, exclude := range [][]string{cfg.ExcludeProjects, opt.Exclude} {
+		if util.ContainsString(&exclude, project.Name) || util.ContainsString(&exclude, project.PathWithNamespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectDir returns the local directory of the project,
+// which mirrors the project path on the GitLab server
+func projectDir(base string, project *gitlab.Project) string {
+	return filepath.Join(base, filepath.FromSlash(project.PathWithNamespace))
+}
+
+// repoAuth returns token based auth for http(s) repository URLs,
+// other transports (ssh, file) use their own auth methods
+func repoAuth(cfg *config.Config, repoURL string) transport.AuthMethod {
+	if !strings.HasPrefix(repoURL, "http://") && !strings.HasPrefix(repoURL, "https://") {
+		return nil
+	}
+	return &githttp.BasicAuth{
+		Username: "oauth2",
+		Password: cfg.GitLabToken,
+	}
 }
@@ -0,0 +1,189 @@
+package operation
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/lexycore/gitlab-tools/internal/config"
+)
+
+// upstream is a bare repository along with the worktree the commits are
+// made in and pushed from
+type upstream struct {
+	t    *testing.T
+	Dir  string
+	work *gogit.Repository
+}
+
+func newUpstream(t *testing.T) *upstream {
+	t.Helper()
+	u := &upstream{t: t, Dir: filepath.Join(t.TempDir(), "upstream.git")}
+	_, err := gogit.PlainInit(u.Dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.work, err = gogit.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = u.work.CreateRemote(&gitconfig.RemoteConfig{Name: gogit.DefaultRemoteName, URLs: []string{u.Dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.commit("README", "initial\n")
+	return u
+}
+
+// commit adds the file to the master branch and pushes it to the bare repository
+func (u *upstream) commit(name, content string) plumbing.Hash {
+	u.t.Helper()
+	worktree, err := u.work.Worktree()
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(worktree.Filesystem.Root(), name), []byte(content), 0644)
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	_, err = worktree.Add(name)
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	hash, err := worktree.Commit("Add "+name, &gogit.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	err = u.work.Push(&gogit.PushOptions{
+		RefSpecs: []gitconfig.RefSpec{"+refs/heads/*:refs/heads/*"},
+	})
+	if err != nil {
+		u.t.Fatal(err)
+	}
+	return hash
+}
+
+func testProject(path string, u *upstream) *gitlab.Project {
+	return &gitlab.Project{
+		Name:              filepath.Base(path),
+		PathWithNamespace: path,
+		HTTPURLToRepo:     u.Dir,
+		DefaultBranch:     "master",
+	}
+}
+
+// newTestClient returns the client of the GitLab stand-in served by the handler
+func newTestClient(t *testing.T, handler http.Handler) *gitlab.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	git, err := gitlab.NewClient("", gitlab.WithBaseURL(server.URL+"/api/v4/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return git
+}
+
+// writeJSON writes the API response, nextPage is set unless it's empty
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}, nextPage string) {
+	w.Header().Set("Content-Type", "application/json")
+	if nextPage != "" {
+		w.Header().Set("X-Next-Page", nextPage)
+	}
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCloneProject(t *testing.T) {
+	u := newUpstream(t)
+	git := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fproject" {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(t, w, map[string]interface{}{
+			"id":                  1,
+			"name":                "project",
+			"path_with_namespace": "group/project",
+			"http_url_to_repo":    u.Dir,
+			"default_branch":      "master",
+		}, "")
+	}))
+	dest := t.TempDir()
+
+	err := Clone(git, &config.Config{}, &config.GitLabPath{Server: "https://gitlab.example.com/", Group: "group", Project: "project"}, &CloneOptions{Destination: dest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dest, "group", "project", "README"))
+	if err != nil || string(b) != "initial\n" {
+		t.Fatalf("README of the clone is %q, %v", b, err)
+	}
+}
+
+func TestCloneExistingSkipped(t *testing.T) {
+	u := newUpstream(t)
+	project := testProject("group/project", u)
+	opt := &CloneOptions{Destination: t.TempDir()}
+
+	result := cloneRepo(&config.Config{}, project, opt)
+	if result.Err != nil || result.Status != StatusCloned {
+		t.Fatalf("first clone: %v", result)
+	}
+	u.commit("NEWS", "news\n")
+	result = cloneRepo(&config.Config{}, project, opt)
+	if result.Status != StatusSkipped || result.Reason != "already exists" {
+		t.Errorf("second clone: %v", result)
+	}
+	if _, err := os.Stat(filepath.Join(result.Dir, "NEWS")); !os.IsNotExist(err) {
+		t.Errorf("skipped checkout was updated: %v", err)
+	}
+}
+
+func TestCloneUpdate(t *testing.T) {
+	u := newUpstream(t)
+	project := testProject("group/project", u)
+	opt := &CloneOptions{Destination: t.TempDir(), Update: true}
+
+	result := cloneRepo(&config.Config{}, project, opt)
+	if result.Err != nil || result.Status != StatusCloned {
+		t.Fatalf("clone: %v", result)
+	}
+	hash := u.commit("NEWS", "news\n")
+
+	result = cloneRepo(&config.Config{}, project, opt)
+	if result.Err != nil || result.Status != StatusUpdated {
+		t.Fatalf("update: %v", result)
+	}
+	repo, err := gogit.PlainOpen(result.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash() != hash {
+		t.Errorf("HEAD is %s, want %s", head.Hash(), hash)
+	}
+
+	result = cloneRepo(&config.Config{}, project, opt)
+	if result.Status != StatusSkipped || result.Reason != "already up to date" {
+		t.Errorf("update of the updated checkout: %v", result)
+	}
+}
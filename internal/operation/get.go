@@ -2,6 +2,8 @@ package operation
 
 import (
 	"fmt"
+	"path"
+	"time"
 
 	"github.com/xanzy/go-gitlab"
 
@@ -9,47 +11,54 @@ import (
 	"github.com/lexycore/gitlab-tools/internal/util"
 )
 
-func GetProjectRepos(git *gitlab.Client, cfg *config.Config) error {
-	group, _, err := git.Groups.GetGroup(cfg.GitLabGroup, nil)
+func GetProjectRepos(git *gitlab.Client, cfg *config.Config) (Projects, error) {
+	projects, err := groupProjects(git, cfg, cfg.GitLabGroup)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for i, repo := range group.Projects {
+	result := make(Projects, 0, len(projects))
+	for _, repo := range projects {
 		if util.ContainsString(&cfg.ExcludeProjects, repo.Name) {
 			continue
 		}
-		fmt.Println(i, ":", repo.Name)
+		result = append(result, newProject(repo))
 	}
-	return nil
+	return result, nil
 }
 
-func GetProjectReposTags(git *gitlab.Client, cfg *config.Config) error {
-	group, _, err := git.Groups.GetGroup(cfg.GitLabGroup, nil)
+func GetProjectReposTags(git *gitlab.Client, cfg *config.Config) (ProjectsTags, error) {
+	projects, err := groupProjects(git, cfg, cfg.GitLabGroup)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	result := make(ProjectsTags, 0, len(projects))
 	opt := &gitlab.ListTagsOptions{}
-	for i, repo := range group.Projects {
+	for _, repo := range projects {
 		if util.ContainsString(&cfg.ExcludeProjects, repo.Name) {
 			continue
 		}
-		fmt.Println(i, ":", repo.Name)
 		tags, _, err := git.Tags.ListTags(repo.PathWithNamespace, opt)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		projectTags := &ProjectTags{
+			Project: repo.PathWithNamespace,
+			Tags:    make([]*Tag, 0, len(tags)),
 		}
 		for _, tag := range tags {
-			fmt.Println("\t-", tag.Commit.CreatedAt, ":", tag.Name, ":", tag.Commit.ID, ":", tag.Release)
+			projectTags.Tags = append(projectTags.Tags, newTag(tag))
 		}
+		result = append(result, projectTags)
 	}
-	return nil
+	return result, nil
 }
 
-func GetProjectReposMRs(git *gitlab.Client, cfg *config.Config) error {
-	group, _, err := git.Groups.GetGroup(cfg.GitLabGroup, nil)
+func GetProjectReposMRs(git *gitlab.Client, cfg *config.Config) (ProjectsMRs, error) {
+	projects, err := groupProjects(git, cfg, cfg.GitLabGroup)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	result := make(ProjectsMRs, 0, len(projects))
 	tagOpt := &gitlab.ListTagsOptions{
 		OrderBy: &v.updated,
 		Sort:    &v.desc,
@@ -58,80 +67,136 @@ func GetProjectReposMRs(git *gitlab.Client, cfg *config.Config) error {
 			PerPage: 1,
 		},
 	}
-	i := 0
-	for _, repo := range group.Projects {
+	for _, repo := range projects {
 		if util.ContainsString(&cfg.ExcludeProjects, repo.Name) {
 			continue
 		}
-		i++
-		fmt.Println(i, ":", repo.Name)
 		tags, _, err := git.Tags.ListTags(repo.PathWithNamespace, tagOpt)
 		if err != nil {
-			return err
-		} // 64b24f9f8744cc4fe47638cb534cf656a164d59c
-		var mrRelease *gitlab.MergeRequest
+			return nil, err
+		}
+		model := cfg.BranchModels.For(repo.PathWithNamespace, repo.DefaultBranch)
+		projectMRs := &ProjectMRs{
+			Project:       repo.PathWithNamespace,
+			Mainline:      model.Mainline,
+			MergeRequests: make([]*MergeRequest, 0),
+		}
+		result = append(result, projectMRs)
 		for _, tag := range tags {
-			fmt.Println("\t-", tag.Commit.CreatedAt, ":", tag.Name, ":", tag.Commit.ID, ":", tag.Release)
-			mrOpt := &gitlab.ListProjectMergeRequestsOptions{
-				ListOptions: gitlab.ListOptions{
-					Page:    1,
-					PerPage: 5,
-				},
-				State:        &v.merged,
-				OrderBy:      &v.created,
-				Sort:         &v.desc,
-				TargetBranch: &v.beta,
-				Search:       nil,
-			}
-			readMRs := true
-			for readMRs {
-				mrs, response, err := git.MergeRequests.ListProjectMergeRequests(repo.PathWithNamespace, mrOpt)
+			projectMRs.Tag = newTag(tag)
+			var mrRelease *gitlab.MergeRequest
+			if model.Release != "" {
+				mrRelease, err = findReleaseMR(git, repo, model)
 				if err != nil {
-					return err
-				}
-				mrOpt.ListOptions.Page = response.NextPage
-				for _, mr := range mrs {
-					if mr.SourceBranch == v.master {
-						mrRelease = mr
-						fmt.Println("\t\t- beta :", mr.CreatedAt, ":", mr.ID, ":", mr.SHA, ":", mr.MergeCommitSHA, ":", mr.Title)
-						readMRs = false
-						break
-					}
+					return nil, err
 				}
 			}
-			mrOpt = &gitlab.ListProjectMergeRequestsOptions{
-				ListOptions: gitlab.ListOptions{
-					Page:    1,
-					PerPage: 20,
-				},
-				State:        &v.merged,
-				OrderBy:      &v.created,
-				Sort:         &v.desc,
-				TargetBranch: &v.master,
-				Search:       nil,
+			if mrRelease != nil {
+				projectMRs.Release = newMergeRequest(mrRelease)
+				projectMRs.MergeRequests, err = mergedUntilRelease(git, repo, model, mrRelease)
+			} else {
+				projectMRs.MergeRequests, err = mergedAfterTag(git, repo, model, tag)
 			}
-			readMRs = true
-			for mrOpt.ListOptions.Page > 0 {
-				mrsM, response, err := git.MergeRequests.ListProjectMergeRequests(repo.PathWithNamespace, mrOpt)
-				if err != nil {
-					return err
-				}
-				mrOpt.ListOptions.Page = response.NextPage
-				for _, mr := range mrsM {
-					if mrRelease != nil {
-						if mrRelease.SHA == mr.MergeCommitSHA {
-							fmt.Println("\t\t--------------------------------------")
-							mrOpt.ListOptions.Page = 0
-							readMRs = false
-						}
-					}
-					fmt.Println("\t\t- master :", mr.CreatedAt, ":", mr.ID, ":", mr.SHA, ":", mr.MergeCommitSHA, ":", mr.Title)
-					if !readMRs {
-						break
-					}
-				}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// findReleaseMR returns the latest merge request of the mainline into a release branch
+func findReleaseMR(git *gitlab.Client, repo *gitlab.Project, model config.BranchModel) (*gitlab.MergeRequest, error) {
+	mrOpt := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: 20,
+		},
+		State:        &v.merged,
+		OrderBy:      &v.created,
+		Sort:         &v.desc,
+		SourceBranch: &model.Mainline,
+	}
+	for mrOpt.ListOptions.Page > 0 {
+		mrs, response, err := git.MergeRequests.ListProjectMergeRequests(repo.PathWithNamespace, mrOpt)
+		if err != nil {
+			return nil, err
+		}
+		mrOpt.ListOptions.Page = response.NextPage
+		for _, mr := range mrs {
+			matched, err := path.Match(model.Release, mr.TargetBranch)
+			if err != nil {
+				return nil, fmt.Errorf("release branch '%s': %w", model.Release, err)
+			}
+			if matched {
+				return mr, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// mergedUntilRelease returns merge requests merged into the mainline after
+// the head commit of the release merge request
+func mergedUntilRelease(git *gitlab.Client, repo *gitlab.Project, model config.BranchModel, mrRelease *gitlab.MergeRequest) ([]*MergeRequest, error) {
+	result := make([]*MergeRequest, 0)
+	mrOpt := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: 20,
+		},
+		State:        &v.merged,
+		OrderBy:      &v.created,
+		Sort:         &v.desc,
+		TargetBranch: &model.Mainline,
+	}
+	for mrOpt.ListOptions.Page > 0 {
+		mrs, response, err := git.MergeRequests.ListProjectMergeRequests(repo.PathWithNamespace, mrOpt)
+		if err != nil {
+			return nil, err
+		}
+		mrOpt.ListOptions.Page = response.NextPage
+		for _, mr := range mrs {
+			if mrRelease.SHA == mr.MergeCommitSHA {
+				return result, nil
+			}
+			result = append(result, newMergeRequest(mr))
+		}
+	}
+	return result, nil
+}
+
+// mergedAfterTag returns merge requests merged into the mainline after the tag commit
+func mergedAfterTag(git *gitlab.Client, repo *gitlab.Project, model config.BranchModel, tag *gitlab.Tag) ([]*MergeRequest, error) {
+	result := make([]*MergeRequest, 0)
+	mrOpt := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: 20,
+		},
+		State:        &v.merged,
+		OrderBy:      &v.created,
+		Sort:         &v.desc,
+		TargetBranch: &model.Mainline,
+	}
+	var since *time.Time
+	if tag.Commit != nil {
+		since = tag.Commit.CreatedAt
+		// merging updates the merge request, so older ones can be skipped
+		mrOpt.UpdatedAfter = since
+	}
+	for mrOpt.ListOptions.Page > 0 {
+		mrs, response, err := git.MergeRequests.ListProjectMergeRequests(repo.PathWithNamespace, mrOpt)
+		if err != nil {
+			return nil, err
+		}
+		mrOpt.ListOptions.Page = response.NextPage
+		for _, mr := range mrs {
+			if since != nil && mr.MergedAt != nil && !mr.MergedAt.After(*since) {
+				continue
 			}
+			result = append(result, newMergeRequest(mr))
 		}
 	}
-	return nil
+	return result, nil
 }
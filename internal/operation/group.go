@@ -0,0 +1,88 @@
+package operation
+
+import (
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/lexycore/gitlab-tools/internal/config"
+)
+
+const perPage = 100
+
+// walkGroups calls fn for the group and for its subgroups, going down to
+// maxDepth levels of nesting. A negative maxDepth means no limit.
+func walkGroups(git *gitlab.Client, gid interface{}, maxDepth int, fn func(group *gitlab.Group) error) error {
+	withProjects := false
+	group, _, err := git.Groups.GetGroup(gid, &gitlab.GetGroupOptions{WithProjects: &withProjects})
+	if err != nil {
+		return err
+	}
+	return walkGroup(git, group, maxDepth, fn)
+}
+
+func walkGroup(git *gitlab.Client, group *gitlab.Group, maxDepth int, fn func(group *gitlab.Group) error) error {
+	err := fn(group)
+	if err != nil {
+		return err
+	}
+	if maxDepth == 0 {
+		return nil
+	}
+	opt := &gitlab.ListSubgroupsOptions{
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: perPage,
+		},
+	}
+	for opt.Page > 0 {
+		subgroups, response, err := git.Groups.ListSubgroups(group.ID, opt)
+		if err != nil {
+			return err
+		}
+		opt.Page = response.NextPage
+		for _, subgroup := range subgroups {
+			err = walkGroup(git, subgroup, maxDepth-1, fn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listGroupProjects returns all direct projects of the group page by page,
+// unlike the projects embedded into GetGroup which are capped by the server
+func listGroupProjects(git *gitlab.Client, gid interface{}) ([]*gitlab.Project, error) {
+	projects := make([]*gitlab.Project, 0)
+	opt := &gitlab.ListGroupProjectsOptions{
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: perPage,
+		},
+	}
+	for opt.Page > 0 {
+		page, response, err := git.Groups.ListGroupProjects(gid, opt)
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = response.NextPage
+		projects = append(projects, page...)
+	}
+	return projects, nil
+}
+
+// groupProjects returns projects of the group and its subgroups
+func groupProjects(git *gitlab.Client, cfg *config.Config, gid interface{}) ([]*gitlab.Project, error) {
+	projects := make([]*gitlab.Project, 0)
+	err := walkGroups(git, gid, cfg.SubgroupDepth, func(group *gitlab.Group) error {
+		page, err := listGroupProjects(git, group.ID)
+		if err != nil {
+			return err
+		}
+		projects = append(projects, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
@@ -0,0 +1,125 @@
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lexycore/gitlab-tools/internal/config"
+)
+
+// fakeGroups serves the groups, their projects and subgroups split into
+// pages, the pages are keyed by the group ID
+type fakeGroups struct {
+	t         *testing.T
+	projects  map[int][][]string
+	subgroups map[int][][]int
+	// requests counts the requested pages by the path
+	requests map[string]int
+}
+
+func (f *fakeGroups) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v4/groups/"), "/")
+	id, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if len(parts) == 1 {
+		writeJSON(f.t, w, map[string]interface{}{"id": id, "full_path": fmt.Sprintf("group%d", id)}, "")
+		return
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	f.requests[r.URL.Path]++
+
+	var items []interface{}
+	pages := 0
+	switch parts[1] {
+	case "projects":
+		pages = len(f.projects[id])
+		if page <= pages {
+			for _, name := range f.projects[id][page-1] {
+				items = append(items, map[string]interface{}{"name": name, "path_with_namespace": fmt.Sprintf("group%d/%s", id, name)})
+			}
+		}
+	case "subgroups":
+		pages = len(f.subgroups[id])
+		if page <= pages {
+			for _, subgroup := range f.subgroups[id][page-1] {
+				items = append(items, map[string]interface{}{"id": subgroup})
+			}
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	next := ""
+	if page < pages {
+		next = strconv.Itoa(page + 1)
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+	writeJSON(f.t, w, items, next)
+}
+
+func TestGroupProjectsPages(t *testing.T) {
+	tests := []struct {
+		depth    int
+		projects []string
+	}{
+		{depth: -1, projects: []string{"a", "b", "c", "d", "e", "sub1", "sub2", "sub3", "subsub"}},
+		{depth: 1, projects: []string{"a", "b", "c", "d", "e", "sub1", "sub2", "sub3"}},
+		{depth: 0, projects: []string{"a", "b", "c", "d", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.depth), func(t *testing.T) {
+			fake := &fakeGroups{
+				t: t,
+				projects: map[int][][]string{
+					1: {{"a", "b"}, {"c"}, {"d", "e"}},
+					2: {{"sub1"}},
+					3: {{"sub2"}},
+					4: {{"sub3"}},
+					5: {{"subsub"}},
+				},
+				subgroups: map[int][][]int{
+					1: {{2}, {3}, {4}},
+					2: {{5}},
+				},
+				requests: make(map[string]int),
+			}
+			git := newTestClient(t, fake)
+
+			projects, err := groupProjects(git, &config.Config{SubgroupDepth: tt.depth}, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(projects))
+			for _, project := range projects {
+				names = append(names, project.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.projects) {
+				t.Errorf("projects are %v, want %v", names, tt.projects)
+			}
+			if n := fake.requests["/api/v4/groups/1/projects"]; n != 3 {
+				t.Errorf("requested %d pages of the projects, want 3", n)
+			}
+			want := 3
+			if tt.depth == 0 {
+				want = 0
+			}
+			if n := fake.requests["/api/v4/groups/1/subgroups"]; n != want {
+				t.Errorf("requested %d pages of the subgroups, want %d", n, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package operation
+
+import (
+	"os"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/lexycore/gitlab-tools/internal/config"
+)
+
+// mirrorRefSpec maps all the remote refs (branches, tags, notes, etc.) as is
+const mirrorRefSpec = gitconfig.RefSpec("+refs/*:refs/*")
+
+// mirrorRepo creates a bare mirror of the project in <group>/<project>.git
+// or updates the existing one
+func mirrorRepo(cfg *config.Config, project *gitlab.Project, opt *CloneOptions) *CloneResult {
+	dir := projectDir(opt.Destination, project) + ".git"
+	result := &CloneResult{
+		Project: project.PathWithNamespace,
+		Dir:     dir,
+	}
+	if project.EmptyRepo {
+		return result.skipped("empty repository")
+	}
+	auth := repoAuth(cfg, project.HTTPURLToRepo)
+	if _, err := os.Stat(dir); err == nil {
+		repo, err := gogit.PlainOpen(dir)
+		if err != nil {
+			return result.failed(err)
+		}
+		changed, err := fetchMirror(repo, auth, &cfg.TLS)
+		if err != nil {
+			return result.failed(err)
+		}
+		if !changed {
+			return result.skipped("already up to date")
+		}
+		result.Status = StatusUpdated
+		return result
+	}
+
+	repo, err := gogit.PlainInit(dir, true)
+	if err != nil {
+		return result.failed(err)
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name:  gogit.DefaultRemoteName,
+		URLs:  []string{project.HTTPURLToRepo},
+		Fetch: []gitconfig.RefSpec{mirrorRefSpec},
+	})
+	if err == nil {
+		_, err = fetchMirror(repo, auth, &cfg.TLS)
+	}
+	if err == nil && project.DefaultBranch != "" {
+		head := plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(project.DefaultBranch))
+		err = repo.Storer.SetReference(head)
+	}
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return result.failed(err)
+	}
+	result.Status = StatusCloned
+	return result
+}
+
+// fetchMirror fetches all the remote refs and prunes the local ones which
+// don't exist on the remote anymore, it reports whether any ref has changed
+func fetchMirror(repo *gogit.Repository, auth transport.AuthMethod, tls *config.TLS) (bool, error) {
+	err := repo.Fetch(&gogit.FetchOptions{
+		RemoteName:      gogit.DefaultRemoteName,
+		RefSpecs:        []gitconfig.RefSpec{mirrorRefSpec},
+		Auth:            auth,
+		Tags:            gogit.NoTags,
+		Force:           true,
+		InsecureSkipTLS: tls.InsecureSkipVerify,
+		CABundle:        tls.CABundle,
+	})
+	changed := err == nil
+	if err != nil && err != gogit.NoErrAlreadyUpToDate {
+		return false, err
+	}
+
+	remote, err := repo.Remote(gogit.DefaultRemoteName)
+	if err != nil {
+		return false, err
+	}
+	remoteRefs, err := remote.List(&gogit.ListOptions{
+		Auth:            auth,
+		InsecureSkipTLS: tls.InsecureSkipVerify,
+		CABundle:        tls.CABundle,
+	})
+	if err != nil {
+		return false, err
+	}
+	exists := make(map[plumbing.ReferenceName]bool, len(remoteRefs))
+	for _, ref := range remoteRefs {
+		exists[ref.Name()] = true
+	}
+	refs, err := repo.References()
+	if err != nil {
+		return false, err
+	}
+	stale := make([]plumbing.ReferenceName, 0)
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Name() != plumbing.HEAD && !exists[ref.Name()] {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, name := range stale {
+		err = repo.Storer.RemoveReference(name)
+		if err != nil {
+			return false, err
+		}
+	}
+	return changed || len(stale) > 0, nil
+}
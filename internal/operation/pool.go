@@ -0,0 +1,127 @@
+package operation
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// CloneStatus describes what has been done to a project
+type CloneStatus string
+
+const (
+	StatusCloned  CloneStatus = "cloned"
+	StatusUpdated CloneStatus = "updated"
+	StatusSkipped CloneStatus = "skipped"
+	StatusFailed  CloneStatus = "failed"
+	// StatusArchived marks local repositories of projects archived on the server
+	StatusArchived CloneStatus = "archived"
+	// StatusStale marks local repositories of projects removed or renamed on the server
+	StatusStale CloneStatus = "stale"
+)
+
+// CloneResult contains the outcome of a clone or an update of a single project
+type CloneResult struct {
+	Project string
+	Dir     string
+	Status  CloneStatus
+	// Reason explains the status, e.g. why the project was skipped
+	Reason string
+	Err    error
+}
+
+func (r *CloneResult) skipped(reason string) *CloneResult {
+	r.Status = StatusSkipped
+	r.Reason = reason
+	return r
+}
+
+func (r *CloneResult) failed(err error) *CloneResult {
+	r.Status = StatusFailed
+	r.Err = err
+	return r
+}
+
+func (r *CloneResult) String() string {
+	switch r.Status {
+	case StatusFailed:
+		return fmt.Sprintf("%s %s : %v", r.Status, r.Project, r.Err)
+	case StatusSkipped, StatusArchived, StatusStale:
+		return fmt.Sprintf("%s %s : %s", r.Status, r.Project, r.Reason)
+	}
+	if r.Reason != "" {
+		return fmt.Sprintf("%s %s in %s : %s", r.Status, r.Project, r.Dir, r.Reason)
+	}
+	if r.Status == StatusCloned {
+		return fmt.Sprintf("%s %s into %s", r.Status, r.Project, r.Dir)
+	}
+	return fmt.Sprintf("%s %s in %s", r.Status, r.Project, r.Dir)
+}
+
+// runPool processes the projects with at most concurrency workers at a time
+// and prints every result as soon as it's ready. The returned results keep the
+// order of the projects.
+func runPool(concurrency int, projects []*gitlab.Project, fn func(project *gitlab.Project) *CloneResult) []*CloneResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]*CloneResult, len(projects))
+	jobs := make(chan int)
+	done := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(projects[i])
+				done <- i
+			}
+		}()
+	}
+	go func() {
+		for i := range projects {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+	for i := range done {
+		fmt.Println(results[i])
+	}
+	return results
+}
+
+// printSummary prints the number of projects per status and the failed ones,
+// it returns an error if any of the projects has failed
+func printSummary(results []*CloneResult) error {
+	counts := make(map[CloneStatus]int)
+	failed := make([]*CloneResult, 0)
+	for _, result := range results {
+		counts[result.Status]++
+		if result.Status == StatusFailed {
+			failed = append(failed, result)
+		}
+	}
+	summary := fmt.Sprintf("%s: %d, %s: %d, %s: %d, %s: %d",
+		StatusCloned, counts[StatusCloned],
+		StatusUpdated, counts[StatusUpdated],
+		StatusSkipped, counts[StatusSkipped],
+		StatusFailed, counts[StatusFailed],
+	)
+	for _, status := range []CloneStatus{StatusArchived, StatusStale} {
+		if counts[status] > 0 {
+			summary += fmt.Sprintf(", %s: %d", status, counts[status])
+		}
+	}
+	fmt.Printf("\n%s\n", summary)
+	for _, result := range failed {
+		fmt.Println("\t-", result.Project, ":", result.Err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d projects failed", len(failed), len(results))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package operation
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+// Project contains the project details reported by get operations
+type Project struct {
+	Name          string `json:"name" yaml:"name"`
+	Path          string `json:"path" yaml:"path"`
+	DefaultBranch string `json:"default_branch" yaml:"default_branch"`
+	WebURL        string `json:"web_url" yaml:"web_url"`
+	Archived      bool   `json:"archived" yaml:"archived"`
+}
+
+// Tag contains the tag details reported by get operations
+type Tag struct {
+	Name    string     `json:"name" yaml:"name"`
+	Commit  string     `json:"commit" yaml:"commit"`
+	Date    *time.Time `json:"date" yaml:"date"`
+	Release string     `json:"release,omitempty" yaml:"release,omitempty"`
+}
+
+// MergeRequest contains the merge request details reported by get operations
+type MergeRequest struct {
+	IID          int        `json:"iid" yaml:"iid"`
+	Title        string     `json:"title" yaml:"title"`
+	Author       string     `json:"author" yaml:"author"`
+	SourceBranch string     `json:"source_branch" yaml:"source_branch"`
+	TargetBranch string     `json:"target_branch" yaml:"target_branch"`
+	MergeCommit  string     `json:"merge_commit" yaml:"merge_commit"`
+	MergedAt     *time.Time `json:"merged_at" yaml:"merged_at"`
+	Labels       []string   `json:"labels" yaml:"labels"`
+	WebURL       string     `json:"web_url" yaml:"web_url"`
+}
+
+// ProjectTags contains tags of a project
+type ProjectTags struct {
+	Project string `json:"project" yaml:"project"`
+	Tags    []*Tag `json:"tags" yaml:"tags"`
+}
+
+// ProjectMRs contains merge requests of a project merged since the latest release
+type ProjectMRs struct {
+	Project  string `json:"project" yaml:"project"`
+	Mainline string `json:"mainline" yaml:"mainline"`
+	Tag      *Tag   `json:"tag" yaml:"tag"`
+	// Release is the merge request of the latest release
+	Release       *MergeRequest   `json:"release" yaml:"release"`
+	MergeRequests []*MergeRequest `json:"merge_requests" yaml:"merge_requests"`
+}
+
+// Projects is the result of GetProjectRepos
+type Projects []*Project
+
+// ProjectsTags is the result of GetProjectReposTags
+type ProjectsTags []*ProjectTags
+
+// ProjectsMRs is the result of GetProjectReposMRs
+type ProjectsMRs []*ProjectMRs
+
+func newProject(project *gitlab.Project) *Project {
+	return &Project{
+		Name:          project.Name,
+		Path:          project.PathWithNamespace,
+		DefaultBranch: project.DefaultBranch,
+		WebURL:        project.WebURL,
+		Archived:      project.Archived,
+	}
+}
+
+func newTag(tag *gitlab.Tag) *Tag {
+	t := &Tag{
+		Name: tag.Name,
+	}
+	if tag.Commit != nil {
+		t.Commit = tag.Commit.ID
+		t.Date = tag.Commit.CreatedAt
+	}
+	if tag.Release != nil {
+		t.Release = tag.Release.Description
+	}
+	return t
+}
+
+func newMergeRequest(mr *gitlab.MergeRequest) *MergeRequest {
+	m := &MergeRequest{
+		IID:          mr.IID,
+		Title:        mr.Title,
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+		MergeCommit:  mr.MergeCommitSHA,
+		MergedAt:     mr.MergedAt,
+		Labels:       mr.Labels,
+		WebURL:       mr.WebURL,
+	}
+	if mr.Author != nil {
+		m.Author = mr.Author.Username
+	}
+	return m
+}
+
+// Header implements output.Tabular
+func (p Projects) Header() []string {
+	return []string{"name", "path", "default_branch", "web_url", "archived"}
+}
+
+// Rows implements output.Tabular
+func (p Projects) Rows() [][]string {
+	rows := make([][]string, 0, len(p))
+	for _, project := range p {
+		rows = append(rows, []string{
+			project.Name,
+			project.Path,
+			project.DefaultBranch,
+			project.WebURL,
+			strconv.FormatBool(project.Archived),
+		})
+	}
+	return rows
+}
+
+// Header implements output.Tabular
+func (p ProjectsTags) Header() []string {
+	return []string{"project", "tag", "commit", "date", "release"}
+}
+
+// Rows implements output.Tabular, there is a row per tag
+func (p ProjectsTags) Rows() [][]string {
+	rows := make([][]string, 0, len(p))
+	for _, project := range p {
+		if len(project.Tags) == 0 {
+			rows = append(rows, []string{project.Project, "", "", "", ""})
+		}
+		for _, tag := range project.Tags {
+			rows = append(rows, []string{project.Project, tag.Name, tag.Commit, formatTime(tag.Date), tag.Release})
+		}
+	}
+	return rows
+}
+
+// Header implements output.Tabular
+func (p ProjectsMRs) Header() []string {
+	return []string{"project", "tag", "iid", "source_branch", "target_branch", "merged_at", "author", "title"}
+}
+
+// Rows implements output.Tabular, there is a row per merge request
+func (p ProjectsMRs) Rows() [][]string {
+	rows := make([][]string, 0, len(p))
+	for _, project := range p {
+		tag := ""
+		if project.Tag != nil {
+			tag = project.Tag.Name
+		}
+		mrs := project.MergeRequests
+		if project.Release != nil {
+			mrs = append([]*MergeRequest{project.Release}, mrs...)
+		}
+		if len(mrs) == 0 {
+			rows = append(rows, []string{project.Project, tag, "", "", "", "", "", ""})
+		}
+		for _, mr := range mrs {
+			rows = append(rows, []string{
+				project.Project,
+				tag,
+				strconv.Itoa(mr.IID),
+				mr.SourceBranch,
+				mr.TargetBranch,
+				formatTime(mr.MergedAt),
+				mr.Author,
+				mr.Title,
+			})
+		}
+	}
+	return rows
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,237 @@
+package operation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/lexycore/gitlab-tools/internal/config"
+)
+
+// archivedDir is the folder inside of the destination, stale repositories are moved to
+const archivedDir = "_archived"
+
+// SyncOptions represents the options of the sync operation
+type SyncOptions struct {
+	CloneOptions
+	// MoveArchived moves local repositories of projects archived, removed or
+	// renamed on the server into the _archived folder of the destination
+	MoveArchived bool
+}
+
+// Sync keeps a local mirror of a group up to date: it fetches existing
+// repositories, fast-forwards their default branches, clones new projects
+// and reports local repositories which don't match active projects anymore
+func Sync(git *gitlab.Client, cfg *config.Config, path *config.GitLabPath, opt *SyncOptions) error {
+	if path.Server == "" {
+		return ErrServerNotFound
+	}
+	if path.Group == "" && path.Project == "" {
+		return ErrRepoNotFound
+	}
+
+	projects, err := groupProjects(git, cfg, path.FullPath())
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(projects))
+	active := make([]*gitlab.Project, 0, len(projects))
+	archived := make([]*gitlab.Project, 0)
+	for _, repo := range projects {
+		known[repo.PathWithNamespace] = true
+		if isExcluded(cfg, &opt.CloneOptions, repo) {
+			continue
+		}
+		if repo.Archived {
+			archived = append(archived, repo)
+			continue
+		}
+		active = append(active, repo)
+	}
+
+	results := runPool(opt.Concurrency, active, func(project *gitlab.Project) *CloneResult {
+		dir := projectDir(opt.Destination, project)
+		if _, err := os.Stat(dir); err != nil {
+			return cloneRepo(cfg, project, &opt.CloneOptions)
+		}
+		return syncRepo(cfg, project, dir)
+	})
+
+	for _, project := range archived {
+		dir := projectDir(opt.Destination, project)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		result := &CloneResult{
+			Project: project.PathWithNamespace,
+			Dir:     dir,
+			Status:  StatusArchived,
+			Reason:  "archived on the server",
+		}
+		result = archiveRepo(opt, result)
+		fmt.Println(result)
+		results = append(results, result)
+	}
+
+	local, err := localRepos(opt.Destination, path.FullPath(), cfg.SubgroupDepth)
+	if err != nil {
+		return err
+	}
+	for _, projectPath := range local {
+		if known[projectPath] {
+			continue
+		}
+		result := &CloneResult{
+			Project: projectPath,
+			Dir:     filepath.Join(opt.Destination, filepath.FromSlash(projectPath)),
+			Status:  StatusStale,
+			Reason:  "removed or renamed on the server",
+		}
+		result = archiveRepo(opt, result)
+		fmt.Println(result)
+		results = append(results, result)
+	}
+
+	return printSummary(results)
+}
+
+func syncRepo(cfg *config.Config, project *gitlab.Project, dir string) *CloneResult {
+	result := &CloneResult{
+		Project: project.PathWithNamespace,
+		Dir:     dir,
+	}
+	auth := repoAuth(cfg, project.HTTPURLToRepo)
+	repo, err := gogit.PlainOpen(dir)
+	if err != nil {
+		return result.failed(err)
+	}
+	err = repo.Fetch(&gogit.FetchOptions{
+		RemoteName:      gogit.DefaultRemoteName,
+		Auth:            auth,
+		Tags:            gogit.AllTags,
+		InsecureSkipTLS: cfg.TLS.InsecureSkipVerify,
+		CABundle:        cfg.TLS.CABundle,
+	})
+	fetched := err == nil
+	if err != nil && err != gogit.NoErrAlreadyUpToDate {
+		return result.failed(err)
+	}
+	// fetchedOnly reports new fetched objects as an update, when the worktree
+	// itself can't be fast-forwarded
+	fetchedOnly := func(reason string) *CloneResult {
+		if !fetched {
+			return result.skipped(reason)
+		}
+		result.Status = StatusUpdated
+		result.Reason = "fetched only, " + reason
+		return result
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return result.failed(err)
+	}
+	defaultBranch := plumbing.NewBranchReferenceName(project.DefaultBranch)
+	if head.Name() != defaultBranch {
+		return fetchedOnly("not on the default branch")
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return result.failed(err)
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return result.failed(err)
+	}
+	if !status.IsClean() {
+		return fetchedOnly("worktree has local changes")
+	}
+	err = worktree.Pull(&gogit.PullOptions{
+		RemoteName:      gogit.DefaultRemoteName,
+		ReferenceName:   defaultBranch,
+		Auth:            auth,
+		InsecureSkipTLS: cfg.TLS.InsecureSkipVerify,
+		CABundle:        cfg.TLS.CABundle,
+	})
+	switch err {
+	case nil:
+		result.Status = StatusUpdated
+		return result
+	case gogit.NoErrAlreadyUpToDate:
+		return fetchedOnly("already up to date")
+	case gogit.ErrNonFastForwardUpdate:
+		return fetchedOnly("default branch has diverged")
+	}
+	return result.failed(err)
+}
+
+// archiveRepo moves the repository into the _archived folder if requested
+func archiveRepo(opt *SyncOptions, result *CloneResult) *CloneResult {
+	if !opt.MoveArchived {
+		return result
+	}
+	target := filepath.Join(opt.Destination, archivedDir, filepath.FromSlash(result.Project))
+	if _, err := os.Stat(target); err == nil {
+		return result.failed(fmt.Errorf("%s already exists", target))
+	}
+	err := os.MkdirAll(filepath.Dir(target), 0o755)
+	if err != nil {
+		return result.failed(err)
+	}
+	err = os.Rename(result.Dir, target)
+	if err != nil {
+		return result.failed(err)
+	}
+	result.Reason += ", moved to " + target
+	result.Dir = target
+	return result
+}
+
+// localRepos returns paths (relative to base) of git repositories found in the
+// group folder, looking no deeper than the subgroup depth allows
+func localRepos(base string, groupPath string, maxDepth int) ([]string, error) {
+	root := filepath.Join(base, filepath.FromSlash(groupPath))
+	repos := make([]string, 0)
+	err := filepath.Walk(root, func(dir string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && dir == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if isRepoDir(dir) {
+			repos = append(repos, groupPath+"/"+filepath.ToSlash(rel))
+			return filepath.SkipDir
+		}
+		// a repository at this level would belong to the subgroup deeper than allowed
+		if maxDepth >= 0 && strings.Count(rel, string(filepath.Separator)) >= maxDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// isRepoDir checks if the directory is a git worktree
+func isRepoDir(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, gogit.GitDirName))
+	return err == nil
+}
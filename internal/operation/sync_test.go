@@ -0,0 +1,209 @@
+package operation
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/lexycore/gitlab-tools/internal/config"
+)
+
+// clonedProject clones the upstream and returns the project along with its checkout
+func clonedProject(t *testing.T, u *upstream) (*gitlab.Project, string) {
+	t.Helper()
+	project := testProject("group/project", u)
+	result := cloneRepo(&config.Config{}, project, &CloneOptions{Destination: t.TempDir()})
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	return project, result.Dir
+}
+
+func headHash(t *testing.T, dir string, name plumbing.ReferenceName) plumbing.Hash {
+	t.Helper()
+	repo, err := gogit.PlainOpen(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(name, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref.Hash()
+}
+
+func TestSyncRepoFastForward(t *testing.T) {
+	u := newUpstream(t)
+	project, dir := clonedProject(t, u)
+	hash := u.commit("NEWS", "news\n")
+
+	result := syncRepo(&config.Config{}, project, dir)
+	if result.Err != nil || result.Status != StatusUpdated || result.Reason != "" {
+		t.Fatalf("sync: %v", result)
+	}
+	if head := headHash(t, dir, plumbing.HEAD); head != hash {
+		t.Errorf("HEAD is %s, want %s", head, hash)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "NEWS")); err != nil {
+		t.Error(err)
+	}
+
+	result = syncRepo(&config.Config{}, project, dir)
+	if result.Status != StatusSkipped || result.Reason != "already up to date" {
+		t.Errorf("sync of the synced checkout: %v", result)
+	}
+}
+
+func TestSyncRepoFetchOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, repo *gogit.Repository, dir string)
+		reason string
+	}{
+		{
+			name: "dirty",
+			change: func(t *testing.T, repo *gogit.Repository, dir string) {
+				err := ioutil.WriteFile(filepath.Join(dir, "README"), []byte("local change\n"), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			reason: "fetched only, worktree has local changes",
+		},
+		{
+			name: "branch",
+			change: func(t *testing.T, repo *gogit.Repository, dir string) {
+				worktree, err := repo.Worktree()
+				if err == nil {
+					err = worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("feature"), Create: true})
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			reason: "fetched only, not on the default branch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUpstream(t)
+			project, dir := clonedProject(t, u)
+			repo, err := gogit.PlainOpen(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := headHash(t, dir, plumbing.HEAD)
+			tt.change(t, repo, dir)
+			hash := u.commit("NEWS", "news\n")
+
+			result := syncRepo(&config.Config{}, project, dir)
+			if result.Err != nil || result.Status != StatusUpdated || result.Reason != tt.reason {
+				t.Fatalf("sync: %v", result)
+			}
+			if head := headHash(t, dir, plumbing.HEAD); head != before {
+				t.Errorf("HEAD moved to %s", head)
+			}
+			if remote := headHash(t, dir, plumbing.NewRemoteReferenceName("origin", "master")); remote != hash {
+				t.Errorf("origin/master is %s, want %s", remote, hash)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	existing, added := newUpstream(t), newUpstream(t)
+	git := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v4/groups/group":
+			writeJSON(t, w, map[string]interface{}{"id": 1, "full_path": "group"}, "")
+		case "/api/v4/groups/1/projects":
+			writeJSON(t, w, []map[string]interface{}{
+				{"name": "existing", "path_with_namespace": "group/existing", "http_url_to_repo": existing.Dir, "default_branch": "master"},
+				{"name": "added", "path_with_namespace": "group/added", "http_url_to_repo": added.Dir, "default_branch": "master"},
+			}, "")
+		case "/api/v4/groups/1/subgroups":
+			writeJSON(t, w, []interface{}{}, "")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	dest := t.TempDir()
+	cfg := &config.Config{SubgroupDepth: -1}
+	result := cloneRepo(cfg, testProject("group/existing", existing), &CloneOptions{Destination: dest})
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	hash := existing.commit("NEWS", "news\n")
+	// the project removed from the server
+	err := os.MkdirAll(filepath.Join(dest, "group", "removed", gogit.GitDirName), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opt := &SyncOptions{CloneOptions: CloneOptions{Destination: dest, Concurrency: 2}, MoveArchived: true}
+	err = Sync(git, cfg, &config.GitLabPath{Server: "https://gitlab.example.com/", Project: "group"}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head := headHash(t, filepath.Join(dest, "group", "existing"), plumbing.HEAD); head != hash {
+		t.Errorf("existing project HEAD is %s, want %s", head, hash)
+	}
+	if !isRepoDir(filepath.Join(dest, "group", "added")) {
+		t.Error("new project isn't cloned")
+	}
+	if _, err := os.Stat(filepath.Join(dest, "group", "removed")); !os.IsNotExist(err) {
+		t.Errorf("stale repository is still in place: %v", err)
+	}
+	if !isRepoDir(filepath.Join(dest, archivedDir, "group", "removed")) {
+		t.Error("stale repository isn't moved to " + archivedDir)
+	}
+}
+
+func TestLocalRepos(t *testing.T) {
+	base := t.TempDir()
+	for _, dir := range []string{"group/a", "group/sub/b", "group/sub/deeper/c", "group/empty"} {
+		path := filepath.Join(base, filepath.FromSlash(dir))
+		if filepath.Base(dir) != "empty" {
+			path = filepath.Join(path, gogit.GitDirName)
+		}
+		err := os.MkdirAll(path, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		depth int
+		repos []string
+	}{
+		{depth: -1, repos: []string{"group/a", "group/sub/b", "group/sub/deeper/c"}},
+		{depth: 0, repos: []string{"group/a"}},
+		{depth: 1, repos: []string{"group/a", "group/sub/b"}},
+		{depth: 2, repos: []string{"group/a", "group/sub/b", "group/sub/deeper/c"}},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.depth), func(t *testing.T) {
+			repos, err := localRepos(base, "group", tt.depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(repos)
+			if !reflect.DeepEqual(repos, tt.repos) {
+				t.Errorf("repos are %v, want %v", repos, tt.repos)
+			}
+		})
+	}
+
+	repos, err := localRepos(base, "missing", -1)
+	if err != nil || len(repos) != 0 {
+		t.Errorf("repos of the missing group are %v, %v", repos, err)
+	}
+}
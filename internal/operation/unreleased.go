@@ -0,0 +1,196 @@
+package operation
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xanzy/go-gitlab"
+
+	"github.com/lexycore/gitlab-tools/internal/config"
+	"github.com/lexycore/gitlab-tools/internal/util"
+)
+
+// Unreleased contains merge requests merged into the default branch of a project
+// which aren't reachable from its latest tag
+type Unreleased struct {
+	Project       string          `json:"project" yaml:"project"`
+	DefaultBranch string          `json:"default_branch" yaml:"default_branch"`
+	Tag           *Tag            `json:"tag" yaml:"tag"`
+	Count         int             `json:"count" yaml:"count"`
+	Authors       []string        `json:"authors" yaml:"authors"`
+	Labels        []string        `json:"labels" yaml:"labels"`
+	MergeRequests []*MergeRequest `json:"merge_requests" yaml:"merge_requests"`
+}
+
+// UnreleasedList is the result of GetUnreleased
+type UnreleasedList []*Unreleased
+
+// Header implements output.Tabular
+func (u UnreleasedList) Header() []string {
+	return []string{"project", "tag", "count", "authors", "labels"}
+}
+
+// Rows implements output.Tabular, there is a row per project
+func (u UnreleasedList) Rows() [][]string {
+	rows := make([][]string, 0, len(u))
+	for _, project := range u {
+		tag := ""
+		if project.Tag != nil {
+			tag = project.Tag.Name
+		}
+		rows = append(rows, []string{
+			project.Project,
+			tag,
+			strconv.Itoa(project.Count),
+			strings.Join(project.Authors, ","),
+			strings.Join(project.Labels, ","),
+		})
+	}
+	return rows
+}
+
+// GetUnreleased lists merge requests merged into the default branch of every
+// project after its latest tag. Commits are compared by ancestry, so a merge
+// request is unreleased if its commit isn't reachable from the tag.
+func GetUnreleased(git *gitlab.Client, cfg *config.Config) (UnreleasedList, error) {
+	projects, err := groupProjects(git, cfg, cfg.GitLabGroup)
+	if err != nil {
+		return nil, err
+	}
+	result := make(UnreleasedList, 0, len(projects))
+	tagOpt := &gitlab.ListTagsOptions{
+		OrderBy: &v.updated,
+		Sort:    &v.desc,
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: 1,
+		},
+	}
+	for _, repo := range projects {
+		if util.ContainsString(&cfg.ExcludeProjects, repo.Name) || repo.EmptyRepo || repo.DefaultBranch == "" {
+			continue
+		}
+		tags, _, err := git.Tags.ListTags(repo.PathWithNamespace, tagOpt)
+		if err != nil {
+			return nil, err
+		}
+		unreleased := &Unreleased{
+			Project:       repo.PathWithNamespace,
+			DefaultBranch: repo.DefaultBranch,
+		}
+		var mrs []*gitlab.MergeRequest
+		if len(tags) > 0 {
+			unreleased.Tag = newTag(tags[0])
+			mrs, err = mergedSinceCommit(git, repo, unreleased.Tag.Commit)
+		} else {
+			mrs, err = mergedInto(git, repo.PathWithNamespace, repo.DefaultBranch, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		unreleased.add(mrs)
+		result = append(result, unreleased)
+	}
+	return result, nil
+}
+
+// mergedSinceCommit returns merge requests merged into the default branch,
+// whose commits aren't reachable from the given one
+func mergedSinceCommit(git *gitlab.Client, repo *gitlab.Project, sha string) ([]*gitlab.MergeRequest, error) {
+	compare, _, err := git.Repositories.Compare(repo.PathWithNamespace, &gitlab.CompareOptions{
+		From: &sha,
+		To:   &repo.DefaultBranch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(compare.Commits) == 0 {
+		return nil, nil
+	}
+	commits := make(map[string]bool, len(compare.Commits))
+	var since *time.Time
+	for _, commit := range compare.Commits {
+		commits[commit.ID] = true
+		if commit.CommittedDate != nil && (since == nil || commit.CommittedDate.Before(*since)) {
+			since = commit.CommittedDate
+		}
+	}
+	return MergedWithCommits(git, repo.PathWithNamespace, repo.DefaultBranch, commits, since)
+}
+
+// MergedWithCommits returns merge requests of the project merged into the
+// target branch (or any branch if it's empty) with one of the commits
+func MergedWithCommits(git *gitlab.Client, project string, target string, commits map[string]bool, since *time.Time) ([]*gitlab.MergeRequest, error) {
+	// a merge request is updated when it's merged, so the oldest new commit
+	// limits the merge requests to look through
+	mrs, err := mergedInto(git, project, target, since)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*gitlab.MergeRequest, 0, len(mrs))
+	for _, mr := range mrs {
+		if commits[mr.MergeCommitSHA] || commits[mr.SquashCommitSHA] || commits[mr.SHA] {
+			result = append(result, mr)
+		}
+	}
+	return result, nil
+}
+
+// mergedInto returns all merge requests merged into the target branch (or
+// any branch if it's empty), updated after the given time if it's set
+func mergedInto(git *gitlab.Client, project string, target string, updatedAfter *time.Time) ([]*gitlab.MergeRequest, error) {
+	result := make([]*gitlab.MergeRequest, 0)
+	mrOpt := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{
+			Page:    1,
+			PerPage: perPage,
+		},
+		State:        &v.merged,
+		OrderBy:      &v.created,
+		Sort:         &v.desc,
+		UpdatedAfter: updatedAfter,
+	}
+	if target != "" {
+		mrOpt.TargetBranch = &target
+	}
+	for mrOpt.ListOptions.Page > 0 {
+		mrs, response, err := git.MergeRequests.ListProjectMergeRequests(project, mrOpt)
+		if err != nil {
+			return nil, err
+		}
+		mrOpt.ListOptions.Page = response.NextPage
+		result = append(result, mrs...)
+	}
+	return result, nil
+}
+
+// add fills the merge requests along with their count, authors and labels
+func (u *Unreleased) add(mrs []*gitlab.MergeRequest) {
+	u.MergeRequests = make([]*MergeRequest, 0, len(mrs))
+	authors := make(map[string]bool)
+	labels := make(map[string]bool)
+	for _, mr := range mrs {
+		item := newMergeRequest(mr)
+		u.MergeRequests = append(u.MergeRequests, item)
+		if item.Author != "" {
+			authors[item.Author] = true
+		}
+		for _, label := range item.Labels {
+			labels[label] = true
+		}
+	}
+	u.Count = len(u.MergeRequests)
+	u.Authors = sortedKeys(authors)
+	u.Labels = sortedKeys(labels)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
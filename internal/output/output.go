@@ -0,0 +1,120 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported output formats, any other value containing "{{" is used as
+// a text/template executed for every item of the result
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+)
+
+// Formats lists the names of the supported output formats
+var Formats = []string{FormatTable, FormatJSON, FormatYAML, FormatCSV}
+
+// Tabular is implemented by results which can be rendered as a table or csv
+type Tabular interface {
+	Header() []string
+	Rows() [][]string
+}
+
+// Render writes the result to w in the given format
+func Render(w io.Writer, format string, result Tabular) error {
+	switch format {
+	case FormatTable, "":
+		return renderTable(w, result)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		// maintainers are "Name <email>"
+		enc.SetEscapeHTML(false)
+		return enc.Encode(result)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		err := enc.Encode(result)
+		if err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatCSV:
+		return renderCSV(w, result)
+	}
+	if strings.Contains(format, "{{") {
+		return renderTemplate(w, format, result)
+	}
+	return fmt.Errorf("unknown output format '%s', use one of %s or a Go template", format, strings.Join(Formats, ", "))
+}
+
+func renderTable(w io.Writer, result Tabular) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := make([]string, 0, len(result.Header()))
+	for _, column := range result.Header() {
+		header = append(header, strings.ToUpper(column))
+	}
+	_, err := fmt.Fprintln(tw, strings.Join(header, "\t"))
+	if err != nil {
+		return err
+	}
+	for _, row := range result.Rows() {
+		_, err = fmt.Fprintln(tw, strings.Join(row, "\t"))
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+func renderCSV(w io.Writer, result Tabular) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(result.Header())
+	if err != nil {
+		return err
+	}
+	err = cw.WriteAll(result.Rows())
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// renderTemplate executes the template for every item of a slice result
+// (or once for any other result) and ends each output with a new line
+func renderTemplate(w io.Writer, text string, result Tabular) error {
+	tpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return err
+	}
+	items := []interface{}{result}
+	value := reflect.ValueOf(result)
+	if value.Kind() == reflect.Slice {
+		items = make([]interface{}, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items = append(items, value.Index(i).Interface())
+		}
+	}
+	for _, item := range items {
+		err = tpl.Execute(w, item)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}